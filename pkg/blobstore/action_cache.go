@@ -0,0 +1,45 @@
+package blobstore
+
+import (
+	"context"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+)
+
+// ActionCache provides typed access to the contents of the Action
+// Cache (AC). Unlike BlobAccess, it operates on ActionResult messages
+// directly, as opposed to buffers.
+type ActionCache interface {
+	Get(ctx context.Context, digest digest.Digest) (*remoteexecution.ActionResult, error)
+	Update(ctx context.Context, digest digest.Digest, actionResult *remoteexecution.ActionResult) error
+}
+
+type blobAccessActionCache struct {
+	blobAccess              BlobAccess
+	maximumMessageSizeBytes int
+}
+
+// NewActionCache creates an ActionCache that is backed by a BlobAccess.
+// ActionResult messages are marshaled and unmarshaled through the
+// buffer layer. Messages larger than the maximum message size are
+// rejected when being read.
+func NewActionCache(blobAccess BlobAccess, maximumMessageSizeBytes int) ActionCache {
+	return &blobAccessActionCache{
+		blobAccess:              blobAccess,
+		maximumMessageSizeBytes: maximumMessageSizeBytes,
+	}
+}
+
+func (ac *blobAccessActionCache) Get(ctx context.Context, digest digest.Digest) (*remoteexecution.ActionResult, error) {
+	actionResult, err := ac.blobAccess.Get(ctx, digest).ToProto(&remoteexecution.ActionResult{}, ac.maximumMessageSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	return actionResult.(*remoteexecution.ActionResult), nil
+}
+
+func (ac *blobAccessActionCache) Update(ctx context.Context, digest digest.Digest, actionResult *remoteexecution.ActionResult) error {
+	return ac.blobAccess.Put(ctx, digest, buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided))
+}
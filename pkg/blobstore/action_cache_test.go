@@ -0,0 +1,95 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestActionCacheGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	blobAccess := mock.NewMockBlobAccess(ctrl)
+	actionCache := blobstore.NewActionCache(blobAccess, 1000)
+	actionDigest := digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 123)
+
+	t.Run("Success", func(t *testing.T) {
+		blobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromProto(&remoteexecution.ActionResult{
+				ExitCode: 42,
+			}, buffer.UserProvided))
+
+		actionResult, err := actionCache.Get(ctx, actionDigest)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(&remoteexecution.ActionResult{
+			ExitCode: 42,
+		}, actionResult))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		blobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := actionCache.Get(ctx, actionDigest)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		// Data that cannot be unmarshaled should be rejected.
+		blobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromByteSlice(&remoteexecution.ActionResult{}, []byte{0xff}, buffer.UserProvided))
+
+		_, err := actionCache.Get(ctx, actionDigest)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestActionCacheUpdate(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	blobAccess := mock.NewMockBlobAccess(ctrl)
+	actionCache := blobstore.NewActionCache(blobAccess, 1000)
+	actionDigest := digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 123)
+
+	t.Run("Success", func(t *testing.T) {
+		blobAccess.EXPECT().Put(ctx, actionDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				actionResult, err := b.ToProto(&remoteexecution.ActionResult{}, 1000)
+				require.NoError(t, err)
+				require.True(t, proto.Equal(&remoteexecution.ActionResult{
+					ExitCode: 42,
+				}, actionResult))
+				return nil
+			})
+
+		require.NoError(t, actionCache.Update(ctx, actionDigest, &remoteexecution.ActionResult{
+			ExitCode: 42,
+		}))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		blobAccess.EXPECT().Put(ctx, actionDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				b.Discard()
+				return status.Error(codes.Internal, "Server on fire")
+			})
+
+		require.Equal(
+			t,
+			status.Error(codes.Internal, "Server on fire"),
+			actionCache.Update(ctx, actionDigest, &remoteexecution.ActionResult{
+				ExitCode: 42,
+			}))
+	})
+}
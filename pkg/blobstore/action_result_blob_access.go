@@ -0,0 +1,47 @@
+package blobstore
+
+import (
+	"context"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+)
+
+type actionResultBlobAccess struct {
+	BlobAccess
+	maximumMessageSizeBytes int
+}
+
+// NewActionResultBlobAccess creates an adapter that allows a BlobAccess
+// that stores opaque objects to be used as an Action Cache (AC).
+// ActionResult messages are validated before being written, and
+// objects that are read are returned as buffers that can be converted
+// back to ActionResult messages using ToProto().
+//
+// Objects that are read from the backend that don't contain a valid
+// ActionResult message are reported as being corrupted. As the
+// backend is treated as an opaque store, no attempt is made to repair
+// them.
+func NewActionResultBlobAccess(base BlobAccess, maximumMessageSizeBytes int) BlobAccess {
+	return &actionResultBlobAccess{
+		BlobAccess:              base,
+		maximumMessageSizeBytes: maximumMessageSizeBytes,
+	}
+}
+
+func (ba *actionResultBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	data, err := ba.BlobAccess.Get(ctx, digest).ToByteSlice(ba.maximumMessageSizeBytes)
+	if err != nil {
+		return buffer.NewBufferFromError(err)
+	}
+	return buffer.NewProtoBufferFromByteSlice(&remoteexecution.ActionResult{}, data, buffer.BackendProvided(buffer.Irreparable(digest)))
+}
+
+func (ba *actionResultBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	actionResult, err := b.ToProto(&remoteexecution.ActionResult{}, ba.maximumMessageSizeBytes)
+	if err != nil {
+		return err
+	}
+	return ba.BlobAccess.Put(ctx, digest, buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided))
+}
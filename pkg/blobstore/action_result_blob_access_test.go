@@ -0,0 +1,80 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestActionResultBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewActionResultBlobAccess(baseBlobAccess, 1000)
+	actionDigest := digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 123)
+	actionResult := &remoteexecution.ActionResult{
+		OutputFiles: []*remoteexecution.OutputFile{
+			{
+				Path: "bazel-out/foo.o",
+				Digest: &remoteexecution.Digest{
+					Hash:      "8b1a9953c4611296a827abf8c47804d7",
+					SizeBytes: 5,
+				},
+			},
+		},
+		ExitCode:  42,
+		StdoutRaw: []byte("Hello"),
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		// Messages should be stored in the backend in marshaled
+		// form, and be unmarshaled when read back.
+		var storedData []byte
+		baseBlobAccess.EXPECT().Put(ctx, actionDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				data, err := b.ToByteSlice(1000)
+				require.NoError(t, err)
+				storedData = data
+				return nil
+			})
+		require.NoError(t, blobAccess.Put(ctx, actionDigest, buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided)))
+
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewValidatedBufferFromByteSlice(storedData))
+		m, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(actionResult, m))
+	})
+
+	t.Run("PutMalformed", func(t *testing.T) {
+		// Data that doesn't contain a valid message should not
+		// be forwarded to the backend.
+		err := blobAccess.Put(ctx, actionDigest, buffer.NewValidatedBufferFromByteSlice([]byte{0xff}))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("GetMalformed", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte{0xff}))
+		_, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.Equal(t, codes.Internal, status.Code(err))
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+		_, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+}
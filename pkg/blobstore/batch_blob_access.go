@@ -0,0 +1,33 @@
+package blobstore
+
+import (
+	"context"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+)
+
+type batchBlobAccessAdapter struct {
+	BlobAccess
+}
+
+// NewBatchBlobAccess converts a BlobAccess to a BatchBlobAccess. If
+// the BlobAccess already supports fetching objects in batches, it is
+// returned as is. Otherwise, an adapter is returned that implements
+// GetBatch() by calling Get() for every object.
+func NewBatchBlobAccess(base BlobAccess) BatchBlobAccess {
+	if batchBlobAccess, ok := base.(BatchBlobAccess); ok {
+		return batchBlobAccess
+	}
+	return batchBlobAccessAdapter{
+		BlobAccess: base,
+	}
+}
+
+func (ba batchBlobAccessAdapter) GetBatch(ctx context.Context, digests digest.Set) map[digest.Digest]buffer.Buffer {
+	buffers := make(map[digest.Digest]buffer.Buffer, digests.Length())
+	for _, blobDigest := range digests.Items() {
+		buffers[blobDigest] = ba.BlobAccess.Get(ctx, blobDigest)
+	}
+	return buffers
+}
@@ -0,0 +1,65 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewBatchBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	digest1 := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+	digest2 := digest.MustNewDigest("hello", "2c5f6b1ffa4e1a5e1a5e1a5e1a5e1a5e", 5)
+	digests := digest.NewSetBuilder().Add(digest1).Add(digest2).Build()
+
+	t.Run("Adapter", func(t *testing.T) {
+		// Backends that don't support batching natively should
+		// have GetBatch() translated to calls to Get().
+		baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+		baseBlobAccess.EXPECT().Get(ctx, digest1).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+		baseBlobAccess.EXPECT().Get(ctx, digest2).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		buffers := blobstore.NewBatchBlobAccess(baseBlobAccess).GetBatch(ctx, digests)
+		require.Len(t, buffers, 2)
+
+		data, err := buffers[digest1].ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+
+		_, err = buffers[digest2].ToByteSlice(100)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("Native", func(t *testing.T) {
+		// Backends that support batching natively should be
+		// returned as is.
+		baseBlobAccess := mock.NewMockBatchBlobAccess(ctrl)
+		baseBlobAccess.EXPECT().GetBatch(ctx, digests).Return(map[digest.Digest]buffer.Buffer{
+			digest1: buffer.NewValidatedBufferFromByteSlice([]byte("Hello")),
+			digest2: buffer.NewValidatedBufferFromByteSlice([]byte("World")),
+		})
+
+		buffers := blobstore.NewBatchBlobAccess(baseBlobAccess).GetBatch(ctx, digests)
+		require.Len(t, buffers, 2)
+
+		data, err := buffers[digest1].ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+
+		data, err = buffers[digest2].ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("World"), data)
+	})
+}
@@ -0,0 +1,39 @@
+package blobstore
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type blackHoleBlobAccess struct{}
+
+// NewBlackHoleBlobAccess creates a BlobAccess that discards all data
+// written to it. Get() always returns codes.NotFound, while
+// FindMissing() reports all objects as missing. This implementation
+// is useful for benchmarking decorators without involving an actual
+// storage backend.
+//
+// Buffers passed to Put() are fully consumed, meaning that their
+// contents are still validated and that any resources associated with
+// them are released.
+func NewBlackHoleBlobAccess() BlobAccess {
+	return blackHoleBlobAccess{}
+}
+
+func (ba blackHoleBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	return buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found"))
+}
+
+func (ba blackHoleBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	return b.IntoWriter(ioutil.Discard)
+}
+
+func (ba blackHoleBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	return digests, nil
+}
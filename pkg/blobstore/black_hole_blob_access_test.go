@@ -0,0 +1,68 @@
+package blobstore_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBlackHoleBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	blobAccess := blobstore.NewBlackHoleBlobAccess()
+	blobDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("Get", func(t *testing.T) {
+		_, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("PutSuccess", func(t *testing.T) {
+		// Streaming buffers should be consumed fully, so that
+		// the underlying reader is closed.
+		reader := mock.NewMockReadCloser(ctrl)
+		reader.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			return copy(p, "Hello"), nil
+		})
+		reader.EXPECT().Read(gomock.Any()).Return(0, io.EOF).AnyTimes()
+		reader.EXPECT().Close()
+
+		require.NoError(t, blobAccess.Put(ctx, blobDigest, buffer.NewCASBufferFromReader(blobDigest, reader, buffer.UserProvided)))
+	})
+
+	t.Run("PutChecksumMismatch", func(t *testing.T) {
+		// As buffers are consumed, their contents should still
+		// be validated.
+		reader := mock.NewMockReadCloser(ctrl)
+		reader.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			return copy(p, "Hallo"), nil
+		})
+		reader.EXPECT().Read(gomock.Any()).Return(0, io.EOF).AnyTimes()
+		reader.EXPECT().Close()
+
+		require.Equal(
+			t,
+			status.Error(codes.InvalidArgument, "Buffer has checksum d1bf93299de1b68e6d382c893bf1215f, while 8b1a9953c4611296a827abf8c47804d7 was expected"),
+			blobAccess.Put(ctx, blobDigest, buffer.NewCASBufferFromReader(blobDigest, reader, buffer.UserProvided)))
+	})
+
+	t.Run("FindMissing", func(t *testing.T) {
+		digests := digest.NewSetBuilder().
+			Add(blobDigest).
+			Add(digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 0)).
+			Build()
+		missing, err := blobAccess.FindMissing(ctx, digests)
+		require.NoError(t, err)
+		require.Equal(t, digests, missing)
+	})
+}
@@ -15,3 +15,19 @@ type BlobAccess interface {
 	Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error
 	FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error)
 }
+
+// BatchBlobAccess is an extension of BlobAccess for data stores that
+// are capable of fetching multiple objects in a single operation. This
+// may be significantly more efficient than calling Get() repeatedly
+// when fetching many small objects.
+//
+// Implementations of BlobAccess that don't support this natively can
+// be converted to BatchBlobAccess using NewBatchBlobAccess().
+type BatchBlobAccess interface {
+	BlobAccess
+
+	// GetBatch returns buffers for all of the objects in the
+	// provided set. Errors for individual objects are reported
+	// through the buffers that are returned.
+	GetBatch(ctx context.Context, digests digest.Set) map[digest.Digest]buffer.Buffer
+}
@@ -3,6 +3,7 @@ package buffer
 import (
 	"io"
 
+	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -32,6 +33,13 @@ type Buffer interface {
 	// function may fail if the buffer is in a known error state in
 	// which the size of the object is unknown.
 	GetSizeBytes() (int64, error)
+	// Return the digest of the object stored in the buffer. This
+	// is only possible for buffers of objects stored in the Content
+	// Addressable Storage (CAS), as their contents are bound to a
+	// digest. The boolean return value denotes whether a digest is
+	// available. Obtaining the digest does not cause the contents of
+	// the buffer to be read or validated.
+	GetDigest() (digest.Digest, bool)
 
 	// Of the public functions below, exactly one must be called to
 	// release any resources associated with the buffer (e.g., an
@@ -49,8 +57,16 @@ type Buffer interface {
 	// unmarshaled message. The caller must use a type assertion to
 	// convert this function's return value back to the appropriate
 	// message type.
+	//
+	// Like ToByteSlice(), this function fails with
+	// FAILED_PRECONDITION if the buffer exceeds the maximum size.
 	ToProto(m proto.Message, maximumSizeBytes int) (proto.Message, error)
 	// Return the full contents of the buffer as a byte slice.
+	//
+	// If the buffer exceeds the maximum size, this function fails
+	// with FAILED_PRECONDITION. This allows callers to distinguish
+	// this case from data integrity errors, and fall back to
+	// processing the buffer in a streaming fashion.
 	ToByteSlice(maximumSizeBytes int) ([]byte, error)
 	// Read the contents of the buffer, starting at a given offset,
 	// as a stream of byte slices. Normally used by the Content
@@ -27,6 +27,10 @@ func (b *casChunkReaderBuffer) GetSizeBytes() (int64, error) {
 	return b.digest.GetSizeBytes(), nil
 }
 
+func (b *casChunkReaderBuffer) GetDigest() (digest.Digest, bool) {
+	return b.digest, true
+}
+
 func (b *casChunkReaderBuffer) toValidatedChunkReader() ChunkReader {
 	return newCASValidatingChunkReader(b.r, b.digest, b.source)
 }
@@ -43,6 +43,10 @@ func (b *casClonedBuffer) GetSizeBytes() (int64, error) {
 	return b.digest.GetSizeBytes(), nil
 }
 
+func (b *casClonedBuffer) GetDigest() (digest.Digest, bool) {
+	return b.digest, true
+}
+
 func (b *casClonedBuffer) toChunkReader(needsValidation bool, maximumChunkSizeBytes int) ChunkReader {
 	b.lock.Lock()
 	if b.consumersRemaining == 0 {
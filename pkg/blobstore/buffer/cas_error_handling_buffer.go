@@ -32,6 +32,10 @@ func (b *casErrorHandlingBuffer) GetSizeBytes() (int64, error) {
 	return b.digest.GetSizeBytes(), nil
 }
 
+func (b *casErrorHandlingBuffer) GetDigest() (digest.Digest, bool) {
+	return b.digest, true
+}
+
 // tryRepeatedly implements the retrying strategy for buffer operations
 // that can safely be retried in their entirety, without causing partial
 // data to be written twice.
@@ -0,0 +1,88 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/buildbarn/bb-storage/pkg/digest"
+)
+
+type casFileBuffer struct {
+	Buffer
+
+	file   *os.File
+	digest digest.Digest
+	source Source
+}
+
+// NewCASBufferFromFile creates a buffer for an object stored in the
+// Content Addressable Storage, whose contents are stored in a file on
+// the local file system. The file is closed once the buffer is
+// consumed or discarded.
+//
+// Calls to IntoWriter() first validate the contents of the file, after
+// which the file is copied into the Writer directly. This permits the
+// use of zero-copy mechanisms such as sendfile() in case the Writer is
+// a network connection. Though this causes the file to be read twice,
+// only the first pass copies data into userspace, which is needed to
+// compute its checksum. All other operations behave identically to
+// buffers created through NewCASBufferFromReader().
+func NewCASBufferFromFile(digest digest.Digest, file *os.File, source Source) Buffer {
+	return &casFileBuffer{
+		Buffer: NewCASBufferFromReader(digest, newFileSectionReader(file, digest), source),
+		file:   file,
+		digest: digest,
+		source: source,
+	}
+}
+
+func (b *casFileBuffer) IntoWriter(w io.Writer) error {
+	defer b.Buffer.Discard()
+
+	// Validate the contents of the file, so that no data is
+	// written in case of size or checksum mismatches.
+	r := newCASValidatingReader(ioutil.NopCloser(newFileSectionReader(b.file, b.digest)), b.digest, b.source)
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+
+	// Copy the contents of the file into the Writer. Provide the
+	// file through an io.LimitedReader, as that is what
+	// implementations of io.ReaderFrom such as net.TCPConn accept
+	// to use sendfile().
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	sizeBytes := b.digest.GetSizeBytes()
+	n, err := io.Copy(w, &io.LimitedReader{R: b.file, N: sizeBytes})
+	if err != nil {
+		return err
+	}
+
+	// io.Copy() does not treat premature EOFs as errors. Don't let
+	// the file being truncated after validation go unnoticed.
+	if n != sizeBytes {
+		return b.source.notifyCASSizeMismatch(b.digest, sizeBytes, n)
+	}
+	return nil
+}
+
+// fileSectionReader is an io.ReadCloser that reads a file up to one
+// byte past the expected size of the object. This allows
+// casValidatingReader to detect files that are too large.
+type fileSectionReader struct {
+	io.SectionReader
+	file *os.File
+}
+
+func newFileSectionReader(file *os.File, digest digest.Digest) *fileSectionReader {
+	return &fileSectionReader{
+		SectionReader: *io.NewSectionReader(file, 0, digest.GetSizeBytes()+1),
+		file:          file,
+	}
+}
+
+func (r *fileSectionReader) Close() error {
+	return r.file.Close()
+}
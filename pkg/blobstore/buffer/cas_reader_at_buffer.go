@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/buildbarn/bb-storage/pkg/digest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type casReaderAtBuffer struct {
+	Buffer
+
+	r      io.ReaderAt
+	digest digest.Digest
+	source Source
+}
+
+// NewCASBufferFromReaderAt creates a buffer for an object stored in the
+// Content Addressable Storage, whose contents may be obtained through
+// an io.ReaderAt. This may, for example, be used to access objects
+// stored in an external object store that supports ranged reads.
+//
+// Calls to ReadAt() are forwarded to the io.ReaderAt directly, meaning
+// that only the part of the object that is requested is read. This
+// makes it impossible to validate the checksum of the object. The only
+// check that is performed is that the requested range lies within the
+// expected size of the object: reads are clamped to that size, and a
+// size mismatch is reported if the io.ReaderAt hits EOF before the end
+// of the requested range. Objects that are larger than expected are not
+// detected. All other operations read the object sequentially and
+// behave identically to buffers created through
+// NewCASBufferFromReader().
+func NewCASBufferFromReaderAt(digest digest.Digest, r io.ReaderAt, source Source) Buffer {
+	return &casReaderAtBuffer{
+		// Read up to one byte past the expected size of the
+		// object. This allows casValidatingReader to detect
+		// objects that are too large.
+		Buffer: NewCASBufferFromReader(digest, ioutil.NopCloser(io.NewSectionReader(r, 0, digest.GetSizeBytes()+1)), source),
+		r:      r,
+		digest: digest,
+		source: source,
+	}
+}
+
+func (b *casReaderAtBuffer) ReadAt(p []byte, off int64) (int, error) {
+	defer b.Buffer.Discard()
+
+	if off < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "Negative read offset: %d", off)
+	}
+	sizeBytes := b.digest.GetSizeBytes()
+	if off > sizeBytes {
+		return 0, io.EOF
+	}
+
+	// Prevent reading past the end of the object, so that the
+	// final read returns io.EOF as expected.
+	pTruncated := p
+	if remaining := sizeBytes - off; int64(len(pTruncated)) > remaining {
+		pTruncated = pTruncated[:remaining]
+	}
+	n, err := b.r.ReadAt(pTruncated, off)
+	if n < len(pTruncated) {
+		if err == io.EOF {
+			return 0, b.source.notifyCASSizeMismatch(b.digest, sizeBytes, off+int64(n))
+		}
+		return 0, err
+	}
+	if len(pTruncated) < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -6,9 +6,6 @@ import (
 
 	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/golang/protobuf/proto"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type casReaderBuffer struct {
@@ -32,6 +29,10 @@ func (b *casReaderBuffer) GetSizeBytes() (int64, error) {
 	return b.digest.GetSizeBytes(), nil
 }
 
+func (b *casReaderBuffer) GetDigest() (digest.Digest, bool) {
+	return b.digest, true
+}
+
 func (b *casReaderBuffer) toValidatedReader() io.ReadCloser {
 	return newCASValidatingReader(b.r, b.digest, b.source)
 }
@@ -79,7 +80,7 @@ func (b *casReaderBuffer) ToByteSlice(maximumSizeBytes int) ([]byte, error) {
 
 	expectedSizeBytes := b.digest.GetSizeBytes()
 	if expectedSizeBytes > int64(maximumSizeBytes) {
-		return nil, status.Errorf(codes.InvalidArgument, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", expectedSizeBytes, maximumSizeBytes)
+		return nil, newBufferTooLargeError(expectedSizeBytes, maximumSizeBytes)
 	}
 	return ioutil.ReadAll(r)
 }
@@ -37,7 +37,7 @@ func newCASValidatingChunkReader(r ChunkReader, digest digest.Digest, source Sou
 func (r *casValidatingChunkReader) checkSize(chunkLength int) error {
 	if int64(chunkLength) > r.bytesRemaining {
 		sizeBytes := r.digest.GetSizeBytes()
-		return r.source.notifyCASTooBig(sizeBytes, sizeBytes+int64(chunkLength)-r.bytesRemaining)
+		return r.source.notifyCASTooBig(r.digest, sizeBytes, sizeBytes+int64(chunkLength)-r.bytesRemaining)
 	}
 	return nil
 }
@@ -64,7 +64,7 @@ func (r *casValidatingChunkReader) maybeFinalize() error {
 	expectedChecksum := r.digest.GetHashBytes()
 	actualChecksum := r.hasher.Sum(nil)
 	if bytes.Compare(expectedChecksum, actualChecksum) != 0 {
-		return r.source.notifyCASHashMismatch(expectedChecksum, actualChecksum)
+		return r.source.notifyCASHashMismatch(r.digest, expectedChecksum, actualChecksum)
 	}
 	r.source.notifyDataValid()
 	return io.EOF
@@ -79,7 +79,7 @@ func (r *casValidatingChunkReader) doRead() ([]byte, error) {
 	if err == io.EOF {
 		// Premature end-of-file.
 		sizeBytes := r.digest.GetSizeBytes()
-		return nil, r.source.notifyCASSizeMismatch(sizeBytes, sizeBytes-r.bytesRemaining)
+		return nil, r.source.notifyCASSizeMismatch(r.digest, sizeBytes, sizeBytes-r.bytesRemaining)
 	} else if err != nil {
 		return nil, err
 	}
@@ -38,7 +38,7 @@ func (r *casValidatingReader) compareChecksum() error {
 	expectedChecksum := r.digest.GetHashBytes()
 	actualChecksum := r.hasher.Sum(nil)
 	if bytes.Compare(expectedChecksum, actualChecksum) != 0 {
-		return r.source.notifyCASHashMismatch(expectedChecksum, actualChecksum)
+		return r.source.notifyCASHashMismatch(r.digest, expectedChecksum, actualChecksum)
 	}
 	return nil
 }
@@ -46,7 +46,7 @@ func (r *casValidatingReader) compareChecksum() error {
 func (r *casValidatingReader) checkSize(n int) error {
 	if int64(n) > r.bytesRemaining {
 		sizeBytes := r.digest.GetSizeBytes()
-		return r.source.notifyCASTooBig(sizeBytes, sizeBytes+int64(n)-r.bytesRemaining)
+		return r.source.notifyCASTooBig(r.digest, sizeBytes, sizeBytes+int64(n)-r.bytesRemaining)
 	}
 	return nil
 }
@@ -63,7 +63,7 @@ func (r *casValidatingReader) doRead(p []byte) (int, error) {
 		// Compare the blob's size and checksum.
 		if r.bytesRemaining != 0 {
 			sizeBytes := r.digest.GetSizeBytes()
-			return 0, r.source.notifyCASSizeMismatch(sizeBytes, sizeBytes-r.bytesRemaining)
+			return 0, r.source.notifyCASSizeMismatch(r.digest, sizeBytes, sizeBytes-r.bytesRemaining)
 		}
 		if err := r.compareChecksum(); err != nil {
 			return 0, err
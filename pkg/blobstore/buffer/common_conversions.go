@@ -59,12 +59,22 @@ func readAtViaChunkReader(r ChunkReader, p []byte, off int64) (int, error) {
 	}
 }
 
+// newBufferTooLargeError creates the error that is returned when the
+// contents of a buffer need to be loaded into memory, but exceed the
+// maximum permitted size. FAILED_PRECONDITION is used to make it
+// distinguishable from data integrity errors, which are returned as
+// INVALID_ARGUMENT or INTERNAL. Unlike RESOURCE_EXHAUSTED, it is not
+// retried by clients, as retrying would yield the same result.
+func newBufferTooLargeError(sizeBytes int64, maximumSizeBytes int) error {
+	return status.Errorf(codes.FailedPrecondition, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", sizeBytes, maximumSizeBytes)
+}
+
 func toByteSliceViaChunkReader(r ChunkReader, digest digest.Digest, maximumSizeBytes int) ([]byte, error) {
 	defer r.Close()
 
 	expectedSizeBytes := digest.GetSizeBytes()
 	if expectedSizeBytes > int64(maximumSizeBytes) {
-		return nil, status.Errorf(codes.InvalidArgument, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", expectedSizeBytes, maximumSizeBytes)
+		return nil, newBufferTooLargeError(expectedSizeBytes, maximumSizeBytes)
 	}
 
 	data := make([]byte, 0, expectedSizeBytes)
@@ -0,0 +1,328 @@
+package buffer
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+	"github.com/golang/protobuf/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChildBufferFetcher is called by buffers created using
+// NewConcatenatingBufferFromDigests() to obtain the contents of one of
+// its children.
+type ChildBufferFetcher func(blobDigest digest.Digest) Buffer
+
+type concatenatingBuffer struct {
+	digests   []digest.Digest
+	offsets   []int64
+	sizeBytes int64
+	fetch     ChildBufferFetcher
+}
+
+// NewConcatenatingBufferFromDigests creates a Buffer whose contents
+// are the concatenation of the contents of an ordered list of blobs.
+// This can, for example, be used to assemble a tarball from entries
+// that are already stored in the Content Addressable Storage.
+//
+// The size of the buffer is computed from the sizes stored in the
+// digests. Children are fetched lazily. Sequential access through
+// IntoWriter(), ToByteSlice(), ToChunkReader() and ToReader() fetches
+// every child at most once and streams its contents. Calls to ReadAt()
+// only fetch the children that overlap with the range that is read.
+// A read fails if a fetched child has a size that differs from the one
+// stored in its digest.
+//
+// No checksum validation is performed on the buffer as a whole, as
+// the resulting data is not bound to a single digest. It is assumed
+// that the buffers returned by the fetcher validate their own
+// contents.
+func NewConcatenatingBufferFromDigests(digests []digest.Digest, fetch ChildBufferFetcher) Buffer {
+	offsets := make([]int64, 0, len(digests))
+	sizeBytes := int64(0)
+	for _, blobDigest := range digests {
+		offsets = append(offsets, sizeBytes)
+		sizeBytes += blobDigest.GetSizeBytes()
+	}
+	return &concatenatingBuffer{
+		digests:   digests,
+		offsets:   offsets,
+		sizeBytes: sizeBytes,
+		fetch:     fetch,
+	}
+}
+
+func (b *concatenatingBuffer) GetSizeBytes() (int64, error) {
+	return b.sizeBytes, nil
+}
+
+func (b *concatenatingBuffer) GetDigest() (digest.Digest, bool) {
+	return digest.BadDigest, false
+}
+
+func (b *concatenatingBuffer) IntoWriter(w io.Writer) error {
+	r := b.toUnvalidatedReader(0)
+	defer r.Close()
+	_, err := io.Copy(w, r)
+	return err
+}
+
+// findChild returns the index of the last child that starts at or
+// before the provided offset.
+func (b *concatenatingBuffer) findChild(off int64) int {
+	i := sort.Search(len(b.offsets), func(i int) bool { return b.offsets[i] > off }) - 1
+	if i < 0 {
+		i = 0
+	}
+	return i
+}
+
+func (b *concatenatingBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "Negative read offset: %d", off)
+	}
+
+	// Start at the last child that starts at or before the
+	// requested offset. Children that turn out to be empty or end
+	// before the offset are skipped.
+	nTotal := 0
+	for i := b.findChild(off); i < len(b.digests) && len(p) > 0; i++ {
+		childDigest := b.digests[i]
+		childSizeBytes := childDigest.GetSizeBytes()
+		childOffset := off - b.offsets[i]
+		if childOffset >= childSizeBytes {
+			continue
+		}
+
+		// Only read the part of the child that overlaps with
+		// the provided buffer.
+		toRead := childSizeBytes - childOffset
+		if toRead > int64(len(p)) {
+			toRead = int64(len(p))
+		}
+		n, err := b.readChildAt(i, p[:toRead], childOffset)
+		nTotal += n
+		if err != nil {
+			return nTotal, err
+		}
+		p = p[n:]
+		off += int64(n)
+	}
+	if len(p) > 0 {
+		return nTotal, io.EOF
+	}
+	return nTotal, nil
+}
+
+// fetchChild obtains the contents of one of the children, ensuring
+// that its size matches the one stored in its digest.
+func (b *concatenatingBuffer) fetchChild(i int) (Buffer, error) {
+	childDigest := b.digests[i]
+	child := b.fetch(childDigest)
+	sizeBytes, err := child.GetSizeBytes()
+	if err != nil {
+		child.Discard()
+		return nil, util.StatusWrapf(err, "Failed to obtain size of child %d with digest %#v", i, childDigest.String())
+	}
+	if expectedSizeBytes := childDigest.GetSizeBytes(); sizeBytes != expectedSizeBytes {
+		child.Discard()
+		return nil, status.Errorf(codes.Internal, "Child %d with digest %#v is %d bytes in size, while %d bytes were expected", i, childDigest.String(), sizeBytes, expectedSizeBytes)
+	}
+	return child, nil
+}
+
+func (b *concatenatingBuffer) readChildAt(i int, p []byte, off int64) (int, error) {
+	child, err := b.fetchChild(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// Reads that end at the end of the child may legitimately
+	// return io.EOF. Any other io.EOF means the child contained
+	// less data than announced. Such errors must not be propagated
+	// as is, as consumers such as io.Copy() would otherwise treat
+	// the truncated stream as a successful read.
+	childDigest := b.digests[i]
+	n, err := child.ReadAt(p, off)
+	if err == io.EOF && n == len(p) {
+		err = nil
+	}
+	if err != nil {
+		if err == io.EOF {
+			return n, status.Errorf(codes.Internal, "Unexpected EOF in child %d with digest %#v: got %d of %d bytes", i, childDigest.String(), off+int64(n), childDigest.GetSizeBytes())
+		}
+		return n, util.StatusWrapf(err, "Failed to read child %d with digest %#v", i, childDigest.String())
+	}
+	return n, nil
+}
+
+func (b *concatenatingBuffer) ToProto(m proto.Message, maximumSizeBytes int) (proto.Message, error) {
+	return toProtoViaByteSlice(b, m, maximumSizeBytes)
+}
+
+func (b *concatenatingBuffer) ToByteSlice(maximumSizeBytes int) ([]byte, error) {
+	if b.sizeBytes > int64(maximumSizeBytes) {
+		return nil, newBufferTooLargeError(b.sizeBytes, maximumSizeBytes)
+	}
+	r := b.toUnvalidatedReader(0)
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *concatenatingBuffer) ToChunkReader(off int64, maximumChunkSizeBytes int) ChunkReader {
+	return b.toUnvalidatedChunkReader(off, maximumChunkSizeBytes)
+}
+
+func (b *concatenatingBuffer) ToReader() io.ReadCloser {
+	return b.toUnvalidatedReader(0)
+}
+
+func (b *concatenatingBuffer) CloneCopy(maximumSizeBytes int) (Buffer, Buffer) {
+	// Buffers are stateless, as children are only fetched when
+	// data is actually read.
+	return b, b
+}
+
+func (b *concatenatingBuffer) CloneStream() (Buffer, Buffer) {
+	return b, b
+}
+
+func (b *concatenatingBuffer) Discard() {}
+
+func (b *concatenatingBuffer) applyErrorHandler(errorHandler ErrorHandler) (replacement Buffer, shouldRetry bool) {
+	// Errors are reported on a per-child basis by the buffers
+	// returned by the fetcher.
+	errorHandler.Done()
+	return b, false
+}
+
+func (b *concatenatingBuffer) toUnvalidatedChunkReader(off int64, maximumChunkSizeBytes int) ChunkReader {
+	if err := validateReaderOffset(b.sizeBytes, off); err != nil {
+		return newErrorChunkReader(err)
+	}
+	return newReaderBackedChunkReader(b.toUnvalidatedReader(off), maximumChunkSizeBytes)
+}
+
+func (b *concatenatingBuffer) toUnvalidatedReader(off int64) io.ReadCloser {
+	if err := validateReaderOffset(b.sizeBytes, off); err != nil {
+		return newErrorReader(err)
+	}
+	i := b.findChild(off)
+	return &concatenatingReader{
+		b:           b,
+		index:       i,
+		childOffset: off - b.offsets[i],
+	}
+}
+
+// concatenatingReader is a ReadCloser that returns the contents of a
+// concatenating buffer sequentially. Every child is fetched at most
+// once, and its contents are streamed using ToReader().
+type concatenatingReader struct {
+	b           *concatenatingBuffer
+	index       int
+	childOffset int64
+
+	child          io.ReadCloser
+	childRemaining int64
+	err            error
+}
+
+// openChild fetches the child at the current index, skipping the data
+// preceding the current offset within that child.
+func (r *concatenatingReader) openChild() error {
+	child, err := r.b.fetchChild(r.index)
+	if err != nil {
+		return err
+	}
+	childReader := child.ToReader()
+	if err := discardFromReader(childReader, r.childOffset); err != nil {
+		childReader.Close()
+		return util.StatusWrapf(err, "Failed to read child %d with digest %#v", r.index, r.b.digests[r.index].String())
+	}
+	r.child = childReader
+	r.childRemaining = r.b.digests[r.index].GetSizeBytes() - r.childOffset
+	return nil
+}
+
+// finishChild closes the current child after all of its data has been
+// read. The child is read until io.EOF, both to detect children that
+// contain more data than announced, and to let the child validate its
+// checksum.
+func (r *concatenatingReader) finishChild(err error) error {
+	childDigest := r.b.digests[r.index]
+	if err == nil {
+		var p [1]byte
+		if _, err = io.ReadFull(r.child, p[:]); err == nil {
+			return status.Errorf(codes.Internal, "Child %d with digest %#v is larger than %d bytes", r.index, childDigest.String(), childDigest.GetSizeBytes())
+		}
+	}
+	if err != io.EOF {
+		return util.StatusWrapf(err, "Failed to read child %d with digest %#v", r.index, childDigest.String())
+	}
+	r.child.Close()
+	r.child = nil
+	r.index++
+	r.childOffset = 0
+	return nil
+}
+
+func (r *concatenatingReader) read(p []byte) (int, error) {
+	for r.child == nil {
+		if r.index >= len(r.b.digests) {
+			return 0, io.EOF
+		}
+		if r.childOffset >= r.b.digests[r.index].GetSizeBytes() {
+			// Don't fetch children that are empty or that
+			// end before the offset at which reading starts.
+			r.index++
+			r.childOffset = 0
+		} else if err := r.openChild(); err != nil {
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > r.childRemaining {
+		p = p[:r.childRemaining]
+	}
+	n, err := r.child.Read(p)
+	r.childRemaining -= int64(n)
+	if r.childRemaining == 0 {
+		return n, r.finishChild(err)
+	}
+	if err != nil {
+		childDigest := r.b.digests[r.index]
+		if err == io.EOF {
+			return n, status.Errorf(codes.Internal, "Unexpected EOF in child %d with digest %#v: got %d of %d bytes", r.index, childDigest.String(), childDigest.GetSizeBytes()-r.childRemaining, childDigest.GetSizeBytes())
+		}
+		return n, util.StatusWrapf(err, "Failed to read child %d with digest %#v", r.index, childDigest.String())
+	}
+	return n, nil
+}
+
+func (r *concatenatingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.read(p)
+	if err != nil {
+		// Errors are sticky, as consumers such as io.ReadFull()
+		// may discard errors that are returned alongside data.
+		r.Close()
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *concatenatingReader) Close() error {
+	if r.child != nil {
+		r.child.Close()
+		r.child = nil
+	}
+	return nil
+}
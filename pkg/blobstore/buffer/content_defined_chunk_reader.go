@@ -0,0 +1,123 @@
+package buffer
+
+import (
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// contentDefinedChunkReaderGear is the table of random values that is
+// used by the rolling hash of contentDefinedChunkReader. It is
+// generated deterministically, so that identical content is split
+// into identical chunks across processes.
+var contentDefinedChunkReaderGear [256]uint64
+
+func init() {
+	// Fill the table using SplitMix64 with a fixed seed.
+	state := uint64(0)
+	for i := range contentDefinedChunkReaderGear {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		contentDefinedChunkReaderGear[i] = z ^ (z >> 31)
+	}
+}
+
+type contentDefinedChunkReader struct {
+	ChunkReader
+	minimumChunkSizeBytes int
+	maximumChunkSizeBytes int
+	mask                  uint64
+
+	pending []byte
+	err     error
+
+	// State of the rolling hash computed over the first scanned
+	// bytes of the pending data.
+	hash    uint64
+	scanned int
+}
+
+// NewContentDefinedChunkReader creates a decorator for ChunkReader that
+// splits data at content-defined boundaries, as opposed to the fixed
+// size boundaries at which data is normally split. This causes blobs
+// with similar contents to be decomposed into mostly identical chunks,
+// which may be used to perform deduplication.
+//
+// Boundaries are determined using a Gear rolling hash, similar to the
+// one used by FastCDC. Except for the final chunk, all chunks have a
+// size between the provided minimum and maximum. Boundaries only
+// depend on the data itself, not on the sizes of the chunks returned
+// by the underlying ChunkReader.
+func NewContentDefinedChunkReader(r ChunkReader, minimumChunkSizeBytes int, maximumChunkSizeBytes int) ChunkReader {
+	if minimumChunkSizeBytes < 1 || maximumChunkSizeBytes < minimumChunkSizeBytes {
+		r.Close()
+		return newErrorChunkReader(status.Errorf(codes.InvalidArgument, "Invalid chunk size bounds: minimum %d bytes, maximum %d bytes", minimumChunkSizeBytes, maximumChunkSizeBytes))
+	}
+
+	// Pick a mask such that boundaries are expected to be placed
+	// at most halfway between the minimum and maximum chunk size.
+	// The mask selects the most significant bits of the hash, as
+	// those depend on the largest number of preceding bytes.
+	bits := uint(0)
+	for uint64(1)<<(bits+1) <= uint64(maximumChunkSizeBytes-minimumChunkSizeBytes)/2 {
+		bits++
+	}
+	mask := uint64(0)
+	if bits > 0 {
+		mask = (uint64(1)<<bits - 1) << (64 - bits)
+	}
+	return &contentDefinedChunkReader{
+		ChunkReader:           r,
+		minimumChunkSizeBytes: minimumChunkSizeBytes,
+		maximumChunkSizeBytes: maximumChunkSizeBytes,
+		mask:                  mask,
+	}
+}
+
+// findBoundary scans the pending data for the next chunk boundary. It
+// returns zero if more data needs to be read to determine the
+// boundary.
+func (r *contentDefinedChunkReader) findBoundary() int {
+	for r.scanned < len(r.pending) {
+		r.hash = r.hash<<1 + contentDefinedChunkReaderGear[r.pending[r.scanned]]
+		r.scanned++
+		if r.scanned >= r.minimumChunkSizeBytes && (r.hash&r.mask == 0 || r.scanned >= r.maximumChunkSizeBytes) {
+			n := r.scanned
+			r.hash = 0
+			r.scanned = 0
+			return n
+		}
+	}
+	return 0
+}
+
+func (r *contentDefinedChunkReader) Read() ([]byte, error) {
+	for {
+		if n := r.findBoundary(); n > 0 {
+			chunk := r.pending[:n]
+			r.pending = r.pending[n:]
+			return chunk, nil
+		}
+
+		if r.err != nil {
+			// No more data is available. Return any
+			// trailing data as the final chunk.
+			if r.err == io.EOF && len(r.pending) > 0 {
+				chunk := r.pending
+				r.pending = nil
+				return chunk, nil
+			}
+			return nil, r.err
+		}
+
+		chunk, err := r.ChunkReader.Read()
+		if err != nil {
+			r.err = err
+		} else {
+			r.pending = append(r.pending, chunk...)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package buffer_test
+
+import (
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readContentDefinedChunks splits data into content-defined chunks,
+// where the data is provided to the ChunkReader in pieces of a given
+// size.
+func readContentDefinedChunks(t *testing.T, data []byte, inputChunkSizeBytes int) [][]byte {
+	r := buffer.NewContentDefinedChunkReader(
+		buffer.NewValidatedBufferFromByteSlice(data).ToChunkReader(0, inputChunkSizeBytes),
+		/* minimum chunk size = */ 1024,
+		/* maximum chunk size = */ 8192)
+	defer r.Close()
+
+	var chunks [][]byte
+	for {
+		chunk, err := r.Read()
+		if err == io.EOF {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, append([]byte(nil), chunk...))
+	}
+}
+
+func TestContentDefinedChunkReader(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	t.Run("BoundedSizes", func(t *testing.T) {
+		// Concatenating all chunks should yield the original
+		// data. All chunks except for the last one should have
+		// a size within the configured bounds.
+		chunks := readContentDefinedChunks(t, data, 1000)
+		var concatenated []byte
+		for i, chunk := range chunks {
+			if i < len(chunks)-1 {
+				require.GreaterOrEqual(t, len(chunk), 1024)
+			}
+			require.LessOrEqual(t, len(chunk), 8192)
+			concatenated = append(concatenated, chunk...)
+		}
+		require.Equal(t, data, concatenated)
+
+		// Not all chunks should have been cut at the maximum
+		// size, as that would indicate that boundaries are not
+		// content-defined.
+		cutAtMaximum := 0
+		for _, chunk := range chunks {
+			if len(chunk) == 8192 {
+				cutAtMaximum++
+			}
+		}
+		require.Less(t, cutAtMaximum, len(chunks)/2)
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		// Boundaries should only depend on the data, not on
+		// the sizes of the chunks returned by the underlying
+		// ChunkReader.
+		expected := readContentDefinedChunks(t, data, 1000)
+		require.Equal(t, expected, readContentDefinedChunks(t, data, 1))
+		require.Equal(t, expected, readContentDefinedChunks(t, data, 65536))
+	})
+
+	t.Run("Deduplication", func(t *testing.T) {
+		// Prepending data should only affect the first chunks.
+		// Boundaries further on should be identical.
+		chunks := readContentDefinedChunks(t, data, 1000)
+		shiftedChunks := readContentDefinedChunks(t, append([]byte("Hello"), data...), 1000)
+		require.Equal(t, chunks[len(chunks)-10:], shiftedChunks[len(shiftedChunks)-10:])
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		require.Empty(t, readContentDefinedChunks(t, nil, 1000))
+	})
+
+	t.Run("InvalidBounds", func(t *testing.T) {
+		r := buffer.NewContentDefinedChunkReader(
+			buffer.NewValidatedBufferFromByteSlice(data).ToChunkReader(0, 1000),
+			/* minimum chunk size = */ 8192,
+			/* maximum chunk size = */ 1024)
+		_, err := r.Read()
+		require.Equal(t, status.Error(codes.InvalidArgument, "Invalid chunk size bounds: minimum 8192 bytes, maximum 1024 bytes"), err)
+		r.Close()
+	})
+}
@@ -3,6 +3,7 @@ package buffer
 import (
 	"io"
 
+	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -22,6 +23,10 @@ func (b errorBuffer) GetSizeBytes() (int64, error) {
 	return 0, b.err
 }
 
+func (b errorBuffer) GetDigest() (digest.Digest, bool) {
+	return digest.BadDigest, false
+}
+
 func (b errorBuffer) IntoWriter(w io.Writer) error {
 	return b.err
 }
@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"io"
+	"os"
+
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+
+	"google.golang.org/grpc/codes"
+)
+
+// NewCASBufferFromFilePath creates a buffer for an object stored in the
+// Content Addressable Storage, whose contents are stored in a file on
+// the local file system. The file is only opened when the contents of
+// the buffer are accessed, and is closed once the buffer is consumed
+// or discarded.
+func NewCASBufferFromFilePath(digest digest.Digest, path string, source Source) Buffer {
+	return NewCASBufferFromReader(digest, &filePathReader{path: path}, source)
+}
+
+// filePathReader is an io.ReadCloser that opens a file on the local
+// file system upon first use.
+type filePathReader struct {
+	path string
+	f    *os.File
+	err  error
+}
+
+func (r *filePathReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.f == nil {
+		f, err := os.Open(r.path)
+		if os.IsNotExist(err) {
+			r.err = util.StatusWrapWithCode(err, codes.NotFound, "Failed to open file")
+			return 0, r.err
+		} else if err != nil {
+			r.err = util.StatusWrapWithCode(err, codes.Internal, "Failed to open file")
+			return 0, r.err
+		}
+		r.f = f
+	}
+
+	n, err := r.f.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = util.StatusWrapWithCode(err, codes.Internal, "Failed to read from file")
+		return n, r.err
+	}
+	return n, err
+}
+
+func (r *filePathReader) Close() error {
+	r.err = io.ErrClosedPipe
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
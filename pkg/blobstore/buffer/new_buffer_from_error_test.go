@@ -93,3 +93,8 @@ func TestNewBufferFromErrorCloneStream(t *testing.T) {
 func TestNewBufferFromErrorDiscard(t *testing.T) {
 	buffer.NewBufferFromError(status.Error(codes.Internal, "I/O error")).Discard()
 }
+
+func TestNewBufferFromErrorGetDigest(t *testing.T) {
+	_, ok := buffer.NewBufferFromError(status.Error(codes.Internal, "I/O error")).GetDigest()
+	require.False(t, ok)
+}
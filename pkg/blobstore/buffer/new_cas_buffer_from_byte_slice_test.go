@@ -73,3 +73,72 @@ func TestNewCASBufferFromByteSliceHashMismatch(t *testing.T) {
 		buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(5)
 	require.Equal(t, status.Error(codes.Internal, "Buffer has checksum 8b1a9953c4611296a827abf8c47804d7, while d41d8cd98f00b204e9800998ecf8427e was expected"), err)
 }
+
+func TestNewCASBufferFromByteSliceGetDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	helloDigest := digest.MustNewDigest("ubuntu1804", "8b1a9953c4611296a827abf8c47804d7", 5)
+	dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+	dataIntegrityCallback.EXPECT().Call(true)
+
+	b := buffer.NewCASBufferFromByteSlice(helloDigest, []byte("Hello"), buffer.BackendProvided(dataIntegrityCallback.Call))
+	d, ok := b.GetDigest()
+	require.True(t, ok)
+	require.Equal(t, helloDigest, d)
+
+	// The digest should be retained when cloning.
+	b1, b2 := b.CloneCopy(10)
+	d, ok = b1.GetDigest()
+	require.True(t, ok)
+	require.Equal(t, helloDigest, d)
+	b1.Discard()
+	data, err := b2.ToByteSlice(10)
+	require.NoError(t, err)
+	require.Equal(t, []byte("Hello"), data)
+}
+
+func TestNewCASBufferFromByteSliceDataIntegrityErrorReporter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blobDigest := digest.MustNewDigest("ubuntu1804", "d41d8cd98f00b204e9800998ecf8427e", 5)
+
+	t.Run("HashMismatch", func(t *testing.T) {
+		// The error reporter should be invoked with the digest
+		// and the mismatching checksums. The repair callback
+		// should still be invoked as usual.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+		var reportedDigests []digest.Digest
+		var reportedErrs []error
+
+		_, err := buffer.NewCASBufferFromByteSlice(
+			blobDigest,
+			[]byte("Hello"),
+			buffer.BackendProvided(dataIntegrityCallback.Call).WithDataIntegrityErrorReporter(
+				func(blobDigest digest.Digest, err error) {
+					reportedDigests = append(reportedDigests, blobDigest)
+					reportedErrs = append(reportedErrs, err)
+				})).ToByteSlice(5)
+		expectedErr := status.Error(codes.Internal, "Buffer has checksum 8b1a9953c4611296a827abf8c47804d7, while d41d8cd98f00b204e9800998ecf8427e was expected")
+		require.Equal(t, expectedErr, err)
+		require.Equal(t, []digest.Digest{blobDigest}, reportedDigests)
+		require.Equal(t, []error{expectedErr}, reportedErrs)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		// The error reporter should not be invoked for valid data.
+		helloDigest := digest.MustNewDigest("ubuntu1804", "8b1a9953c4611296a827abf8c47804d7", 5)
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+
+		data, err := buffer.NewCASBufferFromByteSlice(
+			helloDigest,
+			[]byte("Hello"),
+			buffer.BackendProvided(dataIntegrityCallback.Call).WithDataIntegrityErrorReporter(
+				func(blobDigest digest.Digest, err error) {
+					t.Fatal("Error reporter should not be invoked")
+				})).ToByteSlice(5)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+}
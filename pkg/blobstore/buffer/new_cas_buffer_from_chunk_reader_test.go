@@ -271,7 +271,7 @@ func TestNewCASBufferFromChunkReaderToProto(t *testing.T) {
 			chunkReader,
 			buffer.BackendProvided(dataIntegrityCallback.Call)).
 			ToProto(&remoteexecution.ActionResult{}, len(exampleActionResultBytes)-1)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
 	})
 
 	t.Run("DataCorruption", func(t *testing.T) {
@@ -646,10 +646,10 @@ func TestNewCASBufferFromChunkReaderCloneCopy(t *testing.T) {
 			buffer.BackendProvided(dataIntegrityCallback.Call)).CloneCopy(4)
 
 		_, err := b1.ToByteSlice(10)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
 
 		_, err = b2.ToByteSlice(10)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
 	})
 }
 
@@ -757,3 +757,18 @@ func TestNewCASBufferFromChunkReaderDiscard(t *testing.T) {
 
 	buffer.NewCASBufferFromChunkReader(exampleDigest, chunkReader, buffer.BackendProvided(dataIntegrityCallback.Call)).Discard()
 }
+
+func TestNewCASBufferFromChunkReaderGetDigest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	helloDigest := digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5)
+	chunkReader := mock.NewMockChunkReader(ctrl)
+	chunkReader.EXPECT().Close()
+
+	// Obtaining the digest should not cause any data to be read.
+	b := buffer.NewCASBufferFromChunkReader(helloDigest, chunkReader, buffer.BackendProvided(buffer.Irreparable(helloDigest)))
+	d, ok := b.GetDigest()
+	require.True(t, ok)
+	require.Equal(t, helloDigest, d)
+	b.Discard()
+}
@@ -0,0 +1,91 @@
+package buffer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewCASBufferFromFilePath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	directory, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	helloDigest := digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("Success", func(t *testing.T) {
+		path := filepath.Join(directory, "success")
+		require.NoError(t, ioutil.WriteFile(path, []byte("Hello"), 0644))
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+
+		data, err := buffer.NewCASBufferFromFilePath(
+			helloDigest,
+			path,
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(10)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+
+	t.Run("ReadAt", func(t *testing.T) {
+		path := filepath.Join(directory, "read_at")
+		require.NoError(t, ioutil.WriteFile(path, []byte("Hello"), 0644))
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+
+		var p [3]byte
+		n, err := buffer.NewCASBufferFromFilePath(
+			helloDigest,
+			path,
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ReadAt(p[:], 1)
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Equal(t, []byte("ell"), p[:])
+	})
+
+	t.Run("SizeMismatch", func(t *testing.T) {
+		path := filepath.Join(directory, "size_mismatch")
+		require.NoError(t, ioutil.WriteFile(path, []byte("Hel"), 0644))
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		_, err := buffer.NewCASBufferFromFilePath(
+			helloDigest,
+			path,
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(10)
+		require.Equal(t, status.Error(codes.Internal, "Buffer is 3 bytes in size, while 5 bytes were expected"), err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+
+		_, err := buffer.NewCASBufferFromFilePath(
+			helloDigest,
+			filepath.Join(directory, "nonexistent"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(10)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		// Discarding the buffer should not cause the file to be
+		// opened, meaning it does not need to exist.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+
+		buffer.NewCASBufferFromFilePath(
+			helloDigest,
+			filepath.Join(directory, "nonexistent"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).Discard()
+	})
+}
@@ -0,0 +1,146 @@
+package buffer_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func openTestFile(t *testing.T, directory string, name string, data string) *os.File {
+	path := filepath.Join(directory, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	return f
+}
+
+func TestNewCASBufferFromFileIntoWriter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	directory, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	helloDigest := digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("Success", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+		writer := bytes.NewBuffer(nil)
+
+		err := buffer.NewCASBufferFromFile(
+			helloDigest,
+			openTestFile(t, directory, "success", "Hello"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).IntoWriter(writer)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), writer.Bytes())
+	})
+
+	t.Run("ChecksumFailure", func(t *testing.T) {
+		// No data should be written in case the contents of the
+		// file are invalid.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+		writer := mock.NewMockWriter(ctrl)
+
+		err := buffer.NewCASBufferFromFile(
+			helloDigest,
+			openTestFile(t, directory, "checksum_failure", "Hallo"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).IntoWriter(writer)
+		require.Equal(t, status.Error(codes.Internal, "Buffer has checksum d1bf93299de1b68e6d382c893bf1215f, while 8b1a9953c4611296a827abf8c47804d7 was expected"), err)
+	})
+
+	t.Run("TooBig", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+		writer := mock.NewMockWriter(ctrl)
+
+		err := buffer.NewCASBufferFromFile(
+			helloDigest,
+			openTestFile(t, directory, "too_big", "Hello world"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).IntoWriter(writer)
+		require.Equal(t, status.Error(codes.Internal, "Buffer is at least 6 bytes in size, while 5 bytes were expected"), err)
+	})
+}
+
+func TestNewCASBufferFromFileReadAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	directory, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	helloDigest := digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	for _, test := range []struct {
+		name     string
+		offset   int64
+		size     int
+		expected string
+		err      error
+	}{
+		{"Start", 0, 3, "Hel", nil},
+		{"Middle", 1, 3, "ell", nil},
+		{"End", 2, 3, "llo", nil},
+		{"Full", 0, 5, "Hello", nil},
+		{"PastEnd", 3, 3, "lo", io.EOF},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+			dataIntegrityCallback.EXPECT().Call(true)
+
+			p := make([]byte, test.size)
+			n, err := buffer.NewCASBufferFromFile(
+				helloDigest,
+				openTestFile(t, directory, test.name, "Hello"),
+				buffer.BackendProvided(dataIntegrityCallback.Call)).ReadAt(p, test.offset)
+			require.Equal(t, test.err, err)
+			require.Equal(t, []byte(test.expected), p[:n])
+		})
+	}
+}
+
+func TestNewCASBufferFromFileToByteSlice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	directory, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	helloDigest := digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("Success", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+
+		data, err := buffer.NewCASBufferFromFile(
+			helloDigest,
+			openTestFile(t, directory, "success", "Hello"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(10)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+
+	t.Run("ChecksumFailure", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		_, err := buffer.NewCASBufferFromFile(
+			helloDigest,
+			openTestFile(t, directory, "checksum_failure", "Hallo"),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(10)
+		require.Equal(t, status.Error(codes.Internal, "Buffer has checksum d1bf93299de1b68e6d382c893bf1215f, while 8b1a9953c4611296a827abf8c47804d7 was expected"), err)
+	})
+}
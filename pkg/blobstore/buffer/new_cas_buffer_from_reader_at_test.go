@@ -0,0 +1,134 @@
+package buffer_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// These tests only cover aspects of NewCASBufferFromReaderAt() itself.
+// All operations other than ReadAt() are provided by
+// NewCASBufferFromReader(), for which separate tests exist.
+
+func TestNewCASBufferFromReaderAtReadAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	helloDigest := digest.MustNewDigest("foo", "3e25960a79dbc69b674cd4ec67a72c62", 11)
+
+	t.Run("MultipleOffsets", func(t *testing.T) {
+		// Reads at any offset should only access the part of
+		// the object that is requested. No checksum validation
+		// can be performed, meaning the data integrity callback
+		// should not be invoked.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		for _, e := range []struct {
+			off      int64
+			size     int
+			expected string
+			err      error
+		}{
+			{0, 5, "Hello", nil},
+			{3, 5, "lo wo", nil},
+			{6, 5, "world", nil},
+			{6, 10, "world", io.EOF},
+			{11, 5, "", io.EOF},
+			{12, 5, "", io.EOF},
+		} {
+			p := make([]byte, e.size)
+			n, err := buffer.NewCASBufferFromReaderAt(
+				helloDigest,
+				bytes.NewReader([]byte("Hello world")),
+				buffer.BackendProvided(dataIntegrityCallback.Call)).ReadAt(p, e.off)
+			require.Equal(t, e.err, err)
+			require.Equal(t, e.expected, string(p[:n]))
+		}
+	})
+
+	t.Run("NegativeOffset", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+
+		var p [5]byte
+		_, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello world")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ReadAt(p[:], -1)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Negative read offset: -1"), err)
+	})
+
+	t.Run("SizeMismatch", func(t *testing.T) {
+		// The ReaderAt returns less data than the digest
+		// announces. This should be reported as a data
+		// integrity error.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		var p [5]byte
+		_, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello wor")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ReadAt(p[:], 6)
+		require.Equal(t, status.Error(codes.Internal, "Buffer is 9 bytes in size, while 11 bytes were expected"), err)
+	})
+}
+
+func TestNewCASBufferFromReaderAtToByteSlice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	helloDigest := digest.MustNewDigest("foo", "3e25960a79dbc69b674cd4ec67a72c62", 11)
+
+	t.Run("Success", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(true)
+
+		data, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello world")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello world"), data)
+	})
+
+	t.Run("TooSmall", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		_, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello wor")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Internal, "Buffer is 9 bytes in size, while 11 bytes were expected"), err)
+	})
+
+	t.Run("TooBig", func(t *testing.T) {
+		// Objects that are larger than the size stored in the
+		// digest should be detected as well.
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		_, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello world!")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Internal, "Buffer is at least 12 bytes in size, while 11 bytes were expected"), err)
+	})
+
+	t.Run("HashMismatch", func(t *testing.T) {
+		dataIntegrityCallback := mock.NewMockDataIntegrityCallback(ctrl)
+		dataIntegrityCallback.EXPECT().Call(false)
+
+		_, err := buffer.NewCASBufferFromReaderAt(
+			helloDigest,
+			bytes.NewReader([]byte("Hello World")),
+			buffer.BackendProvided(dataIntegrityCallback.Call)).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Internal, "Buffer has checksum b10a8db164e0754105b7a99be72e3fe5, while 3e25960a79dbc69b674cd4ec67a72c62 was expected"), err)
+	})
+}
@@ -243,7 +243,7 @@ func TestNewCASBufferFromReaderToProto(t *testing.T) {
 			reader,
 			buffer.BackendProvided(dataIntegrityCallback.Call)).
 			ToProto(&remoteexecution.ActionResult{}, len(exampleActionResultBytes)-1)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
 	})
 
 	t.Run("DataCorruption", func(t *testing.T) {
@@ -575,10 +575,10 @@ func TestNewCASBufferFromReaderCloneCopy(t *testing.T) {
 			buffer.BackendProvided(dataIntegrityCallback.Call)).CloneCopy(4)
 
 		_, err := b1.ToByteSlice(10)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
 
 		_, err = b2.ToByteSlice(10)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
 	})
 }
 
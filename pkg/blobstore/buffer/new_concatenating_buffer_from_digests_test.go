@@ -0,0 +1,215 @@
+package buffer_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var concatenatingBufferChildren = []struct {
+	digest digest.Digest
+	data   string
+}{
+	{digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5), "Hello"},
+	{digest.MustNewDigest("foo", "d41d8cd98f00b204e9800998ecf8427e", 0), ""},
+	{digest.MustNewDigest("foo", "a54d88e06612d820bc3be72877c74f257b561b19", 14), "This is a test"},
+	{digest.MustNewDigest("foo", "1d1f71aecd9b2d8127e5a91fc871833fffe58c5c63aceed9f6fd0b71fe732504", 16), "And another test"},
+}
+
+const concatenatingBufferContents = "HelloThis is a testAnd another test"
+
+func newTestConcatenatingBuffer() buffer.Buffer {
+	digests := make([]digest.Digest, 0, len(concatenatingBufferChildren))
+	contents := map[digest.Digest][]byte{}
+	for _, child := range concatenatingBufferChildren {
+		digests = append(digests, child.digest)
+		contents[child.digest] = []byte(child.data)
+	}
+	return buffer.NewConcatenatingBufferFromDigests(digests, func(blobDigest digest.Digest) buffer.Buffer {
+		return buffer.NewCASBufferFromByteSlice(blobDigest, contents[blobDigest], buffer.UserProvided)
+	})
+}
+
+func TestNewConcatenatingBufferFromDigestsGetSizeBytes(t *testing.T) {
+	b := newTestConcatenatingBuffer()
+	n, err := b.GetSizeBytes()
+	require.NoError(t, err)
+	require.Equal(t, int64(len(concatenatingBufferContents)), n)
+	b.Discard()
+}
+
+func TestNewConcatenatingBufferFromDigestsReadAt(t *testing.T) {
+	t.Run("RandomOffsets", func(t *testing.T) {
+		// Perform reads at random offsets and of random
+		// lengths. Many of these span the boundaries between
+		// children, including the empty one.
+		r := rand.New(rand.NewSource(0))
+		for i := 0; i < 1000; i++ {
+			off := r.Intn(len(concatenatingBufferContents) + 1)
+			p := make([]byte, r.Intn(len(concatenatingBufferContents)+1))
+			n, err := newTestConcatenatingBuffer().ReadAt(p, int64(off))
+			expected := concatenatingBufferContents[off:]
+			if len(expected) >= len(p) {
+				require.NoError(t, err)
+				expected = expected[:len(p)]
+			} else {
+				require.Equal(t, io.EOF, err)
+			}
+			require.Equal(t, len(expected), n)
+			require.Equal(t, expected, string(p[:n]))
+		}
+	})
+
+	t.Run("NegativeOffset", func(t *testing.T) {
+		var p [5]byte
+		_, err := newTestConcatenatingBuffer().ReadAt(p[:], -1)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Negative read offset: -1"), err)
+	})
+
+	t.Run("ChildSizeMismatch", func(t *testing.T) {
+		// Children returned by the fetcher must have the size
+		// that is stored in the digest. Otherwise offsets
+		// within the buffer would be computed incorrectly.
+		b := buffer.NewConcatenatingBufferFromDigests(
+			[]digest.Digest{digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 6)},
+			func(blobDigest digest.Digest) buffer.Buffer {
+				return buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))
+			})
+		var p [6]byte
+		_, err := b.ReadAt(p[:], 0)
+		require.Equal(t, status.Error(codes.Internal, "Child 0 with digest \"8b1a9953c4611296a827abf8c47804d7-6-foo\" is 5 bytes in size, while 6 bytes were expected"), err)
+	})
+
+	t.Run("ChildFetchFailure", func(t *testing.T) {
+		b := buffer.NewConcatenatingBufferFromDigests(
+			[]digest.Digest{
+				digest.MustNewDigest("foo", "8b1a9953c4611296a827abf8c47804d7", 5),
+				digest.MustNewDigest("foo", "a54d88e06612d820bc3be72877c74f257b561b19", 14),
+			},
+			func(blobDigest digest.Digest) buffer.Buffer {
+				if blobDigest.GetSizeBytes() == 5 {
+					return buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))
+				}
+				return buffer.NewBufferFromError(status.Error(codes.Unavailable, "Server offline"))
+			})
+		var p [10]byte
+		n, err := b.ReadAt(p[:], 0)
+		require.Equal(t, 5, n)
+		require.Equal(t, status.Error(codes.Unavailable, "Failed to obtain size of child 1 with digest \"a54d88e06612d820bc3be72877c74f257b561b19-14-foo\": Server offline"), err)
+	})
+}
+
+func TestNewConcatenatingBufferFromDigestsToByteSlice(t *testing.T) {
+	data, err := newTestConcatenatingBuffer().ToByteSlice(100)
+	require.NoError(t, err)
+	require.Equal(t, []byte(concatenatingBufferContents), data)
+}
+
+func TestNewConcatenatingBufferFromDigestsToChunkReader(t *testing.T) {
+	// Chunks should be returned across child boundaries.
+	r := newTestConcatenatingBuffer().ToChunkReader(
+		/* offset = */ 3,
+		/* chunk size = */ 10)
+	chunk, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, []byte("loThis is "), chunk)
+	chunk, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, []byte("a testAnd "), chunk)
+	chunk, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, []byte("another te"), chunk)
+	chunk, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, []byte("st"), chunk)
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+	r.Close()
+}
+
+func TestNewConcatenatingBufferFromDigestsFetchCount(t *testing.T) {
+	// Sequential reads should fetch every child only once, even if
+	// the chunk size is smaller than the children. Empty children
+	// should not be fetched at all.
+	digests := make([]digest.Digest, 0, len(concatenatingBufferChildren))
+	contents := map[digest.Digest][]byte{}
+	for _, child := range concatenatingBufferChildren {
+		digests = append(digests, child.digest)
+		contents[child.digest] = []byte(child.data)
+	}
+	fetchCount := 0
+	r := buffer.NewConcatenatingBufferFromDigests(digests, func(blobDigest digest.Digest) buffer.Buffer {
+		fetchCount++
+		return buffer.NewCASBufferFromByteSlice(blobDigest, contents[blobDigest], buffer.UserProvided)
+	}).ToChunkReader(
+		/* offset = */ 0,
+		/* chunk size = */ 2)
+	var data []byte
+	for {
+		chunk, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data = append(data, chunk...)
+	}
+	r.Close()
+	require.Equal(t, []byte(concatenatingBufferContents), data)
+	require.Equal(t, 3, fetchCount)
+}
+
+func TestNewConcatenatingBufferFromDigestsToReader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	t.Run("Success", func(t *testing.T) {
+		r := newTestConcatenatingBuffer().ToReader()
+		writer := bytes.NewBuffer(nil)
+		_, err := io.Copy(writer, r)
+		require.NoError(t, err)
+		require.Equal(t, []byte(concatenatingBufferContents), writer.Bytes())
+		require.NoError(t, r.Close())
+	})
+
+	t.Run("ChildUnderDelivers", func(t *testing.T) {
+		// A fetcher that returns less data for the final child
+		// than its digest announces. This must not be reported
+		// as a plain io.EOF, as io.Copy() would then consider
+		// the truncated stream to be complete.
+		lastChild := concatenatingBufferChildren[len(concatenatingBufferChildren)-1]
+		digests := make([]digest.Digest, 0, len(concatenatingBufferChildren))
+		contents := map[digest.Digest][]byte{}
+		for _, child := range concatenatingBufferChildren {
+			digests = append(digests, child.digest)
+			contents[child.digest] = []byte(child.data)
+		}
+		reader := mock.NewMockReadAtCloser(ctrl)
+		gomock.InOrder(
+			reader.EXPECT().ReadAt(gomock.Any(), int64(0)).DoAndReturn(func(p []byte, off int64) (int, error) {
+				return copy(p, []byte("And another")), io.EOF
+			}),
+			reader.EXPECT().Close(),
+		)
+
+		r := buffer.NewConcatenatingBufferFromDigests(digests, func(blobDigest digest.Digest) buffer.Buffer {
+			if blobDigest == lastChild.digest {
+				return buffer.NewValidatedBufferFromReaderAt(reader, blobDigest.GetSizeBytes())
+			}
+			return buffer.NewCASBufferFromByteSlice(blobDigest, contents[blobDigest], buffer.UserProvided)
+		}).ToReader()
+		writer := bytes.NewBuffer(nil)
+		_, err := io.Copy(writer, r)
+		require.Equal(t, status.Error(codes.Internal, "Unexpected EOF in child 3 with digest \"1d1f71aecd9b2d8127e5a91fc871833fffe58c5c63aceed9f6fd0b71fe732504-16-foo\": got 11 of 16 bytes"), err)
+		require.Equal(t, []byte("HelloThis is a testAnd another"), writer.Bytes())
+		require.NoError(t, r.Close())
+	})
+}
@@ -92,7 +92,7 @@ func TestNewProtoBufferFromProtoToProto(t *testing.T) {
 	t.Run("TooBig", func(t *testing.T) {
 		_, err := buffer.NewProtoBufferFromProto(&exampleActionResultMessage, buffer.UserProvided).
 			ToProto(&remoteexecution.ActionResult{}, len(exampleActionResultBytes)-1)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
 	})
 }
 
@@ -111,7 +111,7 @@ func TestNewProtoBufferFromProtoToByteSlice(t *testing.T) {
 
 	t.Run("TooBig", func(t *testing.T) {
 		_, err := buffer.NewProtoBufferFromProto(&exampleActionResultMessage, buffer.UserProvided).ToByteSlice(len(exampleActionResultBytes) - 1)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
 	})
 }
 
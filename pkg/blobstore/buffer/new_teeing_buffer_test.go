@@ -0,0 +1,94 @@
+package buffer_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewTeeingBufferToChunkReader(t *testing.T) {
+	helloDigest := digest.MustNewDigest("instance", "3e25960a79dbc69b674cd4ec67a72c62", 11)
+
+	t.Run("Success", func(t *testing.T) {
+		// The tee should receive the full contents of the
+		// blob exactly once.
+		tee := bytes.NewBuffer(nil)
+		r := buffer.NewTeeingBuffer(
+			buffer.NewCASBufferFromReader(helloDigest, ioutil.NopCloser(bytes.NewBufferString("Hello world")), buffer.UserProvided),
+			tee).ToChunkReader(0, 5)
+		chunk, err := r.Read()
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), chunk)
+		chunk, err = r.Read()
+		require.NoError(t, err)
+		require.Equal(t, []byte(" worl"), chunk)
+		chunk, err = r.Read()
+		require.NoError(t, err)
+		require.Equal(t, []byte("d"), chunk)
+		_, err = r.Read()
+		require.Equal(t, io.EOF, err)
+		r.Close()
+
+		require.Equal(t, []byte("Hello world"), tee.Bytes())
+	})
+
+	t.Run("NonZeroOffset", func(t *testing.T) {
+		// Partial reads should not be copied into the tee.
+		tee := bytes.NewBuffer(nil)
+		r := buffer.NewTeeingBuffer(
+			buffer.NewCASBufferFromReader(helloDigest, ioutil.NopCloser(bytes.NewBufferString("Hello world")), buffer.UserProvided),
+			tee).ToChunkReader(6, 100)
+		chunk, err := r.Read()
+		require.NoError(t, err)
+		require.Equal(t, []byte("world"), chunk)
+		_, err = r.Read()
+		require.Equal(t, io.EOF, err)
+		r.Close()
+
+		require.Empty(t, tee.Bytes())
+	})
+
+	t.Run("CloneStream", func(t *testing.T) {
+		// When the buffer is cloned and both copies are
+		// consumed, the tee should still only receive a single
+		// copy of the data.
+		tee := bytes.NewBuffer(nil)
+		b1, b2 := buffer.NewTeeingBuffer(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello world")),
+			tee).CloneStream()
+		for _, b := range []buffer.Buffer{b1, b2} {
+			data, err := b.ToByteSlice(100)
+			require.NoError(t, err)
+			require.Equal(t, []byte("Hello world"), data)
+		}
+
+		require.Equal(t, []byte("Hello world"), tee.Bytes())
+	})
+
+	t.Run("TeeFailure", func(t *testing.T) {
+		// Failures of the tee should not affect the reader.
+		r := buffer.NewTeeingBuffer(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello world")),
+			failingWriter{}).ToChunkReader(0, 100)
+		chunk, err := r.Read()
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello world"), chunk)
+		_, err = r.Read()
+		require.Equal(t, io.EOF, err)
+		r.Close()
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, status.Error(codes.Internal, "Disk on fire")
+}
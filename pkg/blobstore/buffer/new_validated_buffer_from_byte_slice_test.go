@@ -64,7 +64,7 @@ func TestNewValidatedBufferFromByteSliceToProto(t *testing.T) {
 	t.Run("TooBig", func(t *testing.T) {
 		_, err := buffer.NewValidatedBufferFromByteSlice(exampleActionResultBytes).
 			ToProto(&remoteexecution.ActionResult{}, len(exampleActionResultBytes)-1)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 134 bytes in size, while a maximum of 133 bytes is permitted"), err)
 	})
 
 	t.Run("Failure", func(t *testing.T) {
@@ -89,7 +89,7 @@ func TestNewValidatedBufferFromByteSliceToByteSlice(t *testing.T) {
 
 	t.Run("TooBig", func(t *testing.T) {
 		_, err := buffer.NewValidatedBufferFromByteSlice([]byte("Hello")).ToByteSlice(4)
-		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
+		require.Equal(t, status.Error(codes.FailedPrecondition, "Buffer is 5 bytes in size, while a maximum of 4 bytes is permitted"), err)
 	})
 }
 
@@ -197,3 +197,9 @@ func TestNewValidatedBufferFromByteSliceCloneStream(t *testing.T) {
 func TestNewValidatedBufferFromByteSliceDiscard(t *testing.T) {
 	buffer.NewValidatedBufferFromByteSlice([]byte("Hello")).Discard()
 }
+
+func TestNewValidatedBufferFromByteSliceGetDigest(t *testing.T) {
+	// Validated buffers aren't bound to a digest.
+	_, ok := buffer.NewValidatedBufferFromByteSlice([]byte("Hello")).GetDigest()
+	require.False(t, ok)
+}
@@ -5,9 +5,6 @@ import (
 	"io/ioutil"
 
 	"github.com/golang/protobuf/proto"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // protoBuffer stores a copy of valid objects contained in data stores
@@ -70,7 +67,7 @@ func NewProtoBufferFromReader(m proto.Message, r io.ReadCloser, source Source) B
 
 func (b *protoBuffer) ToProto(m proto.Message, maximumSizeBytes int) (proto.Message, error) {
 	if len(b.validatedByteSliceBuffer.data) > maximumSizeBytes {
-		return nil, status.Errorf(codes.InvalidArgument, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", len(b.data), maximumSizeBytes)
+		return nil, newBufferTooLargeError(int64(len(b.data)), maximumSizeBytes)
 	}
 	return b.message, nil
 }
@@ -38,62 +38,97 @@ func Irreparable(blobDigest digest.Digest) DataIntegrityCallback {
 	}
 }
 
+// DataIntegrityErrorReporter is a callback that is invoked by Buffer
+// whenever the contents of a Buffer fail data integrity checking. In
+// addition to the digest of the object, it receives an error that
+// describes the inconsistency (e.g., the expected and observed
+// checksums). As the digest is obtained from the Buffer, it is only
+// invoked for objects stored in the Content Addressable Storage.
+//
+// Unlike DataIntegrityCallback, this callback is not intended to be
+// used to repair data. It may be used to keep a record of which
+// objects were found to be corrupted.
+type DataIntegrityErrorReporter func(blobDigest digest.Digest, err error)
+
+// LogDataIntegrityErrors is an implementation of
+// DataIntegrityErrorReporter that writes details on data integrity
+// errors to the log.
+func LogDataIntegrityErrors(blobDigest digest.Digest, err error) {
+	log.Printf("Digest %#v of %d bytes is corrupted: %s", blobDigest.String(), blobDigest.GetSizeBytes(), err)
+}
+
 // Source is passed to most New*Buffer() creation functions to specify
 // information where the data contained in the buffer originated.
 type Source struct {
 	errorCode             codes.Code
 	dataIntegrityCallback DataIntegrityCallback
+	errorReporter         DataIntegrityErrorReporter
+}
+
+// WithDataIntegrityErrorReporter returns a copy of the Source that
+// additionally invokes a DataIntegrityErrorReporter whenever data
+// integrity errors are detected. This does not alter the way the
+// DataIntegrityCallback is invoked.
+func (s Source) WithDataIntegrityErrorReporter(errorReporter DataIntegrityErrorReporter) Source {
+	s.errorReporter = errorReporter
+	return s
 }
 
 func (s Source) notifyDataValid() {
 	s.dataIntegrityCallback(true)
 }
 
+// notifyDataInvalid reports a data integrity error, and triggers a
+// repair of the data. The digest of the object is only known for
+// objects stored in the Content Addressable Storage.
+func (s Source) notifyDataInvalid(blobDigest digest.Digest, err error) error {
+	if s.errorReporter != nil && blobDigest != digest.BadDigest {
+		s.errorReporter(blobDigest, err)
+	}
+	s.dataIntegrityCallback(false)
+	return err
+}
+
 // notifyProtoMarshalFailure triggers a repair due to a Protobuf message
 // failing to be marshaled properly.
 func (s Source) notifyProtoMarshalFailure(marshalErr error) error {
-	s.dataIntegrityCallback(false)
-	return util.StatusWrapWithCode(marshalErr, s.errorCode, "Failed to marshal message")
+	return s.notifyDataInvalid(digest.BadDigest, util.StatusWrapWithCode(marshalErr, s.errorCode, "Failed to marshal message"))
 }
 
 // notifyProtoUnmarshalFailure triggers a repair due to a Protobuf
 // message failing to be unmarshaled properly.
 func (s Source) notifyProtoUnmarshalFailure(unmarshalErr error) error {
-	s.dataIntegrityCallback(false)
-	return util.StatusWrapWithCode(unmarshalErr, s.errorCode, "Failed to unmarshal message")
+	return s.notifyDataInvalid(digest.BadDigest, util.StatusWrapWithCode(unmarshalErr, s.errorCode, "Failed to unmarshal message"))
 }
 
 // notifyCASTooBig triggers a repair due to a Content Addressable
 // Storage object being larger than expected.
-func (s Source) notifyCASTooBig(sizeExpected int64, sizeObserved int64) error {
-	s.dataIntegrityCallback(false)
-	return status.Errorf(
+func (s Source) notifyCASTooBig(blobDigest digest.Digest, sizeExpected int64, sizeObserved int64) error {
+	return s.notifyDataInvalid(blobDigest, status.Errorf(
 		s.errorCode,
 		"Buffer is at least %d bytes in size, while %d bytes were expected",
 		sizeObserved,
-		sizeExpected)
+		sizeExpected))
 }
 
 // notifyCASSizeMismatch triggers a repair due to a Content Addressable
 // Storage object having the wrong exact size.
-func (s Source) notifyCASSizeMismatch(sizeExpected int64, sizeObserved int64) error {
-	s.dataIntegrityCallback(false)
-	return status.Errorf(
+func (s Source) notifyCASSizeMismatch(blobDigest digest.Digest, sizeExpected int64, sizeObserved int64) error {
+	return s.notifyDataInvalid(blobDigest, status.Errorf(
 		s.errorCode,
 		"Buffer is %d bytes in size, while %d bytes were expected",
 		sizeObserved,
-		sizeExpected)
+		sizeExpected))
 }
 
 // notifyCASHashMismatch triggers a repair due to a Content Addressable
 // Storage object having the wrong cryptographic checksum.
-func (s Source) notifyCASHashMismatch(hashExpected []byte, hashObserved []byte) error {
-	s.dataIntegrityCallback(false)
-	return status.Errorf(
+func (s Source) notifyCASHashMismatch(blobDigest digest.Digest, hashExpected []byte, hashObserved []byte) error {
+	return s.notifyDataInvalid(blobDigest, status.Errorf(
 		s.errorCode,
 		"Buffer has checksum %s, while %s was expected",
 		hex.EncodeToString(hashObserved),
-		hex.EncodeToString(hashExpected))
+		hex.EncodeToString(hashExpected)))
 }
 
 var (
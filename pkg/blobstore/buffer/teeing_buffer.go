@@ -0,0 +1,179 @@
+package buffer
+
+import (
+	"io"
+
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/protobuf/proto"
+)
+
+// teeWriter is a wrapper around the io.Writer provided to
+// NewTeeingBuffer(). Once writing to the tee fails, it stops
+// forwarding data to it, as a partial copy is of no use.
+type teeWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+func (t *teeWriter) write(p []byte) {
+	if !t.failed && len(p) > 0 {
+		if _, err := t.w.Write(p); err != nil {
+			t.failed = true
+		}
+	}
+}
+
+type teeingBuffer struct {
+	base Buffer
+	tee  *teeWriter
+}
+
+// NewTeeingBuffer returns a decorated Buffer that writes a copy of its
+// contents into a secondary io.Writer while it is being read. This
+// may, for example, be used to populate a cache while serving a read.
+//
+// Only operations that read the buffer from the start
+// (IntoWriter(), ToProto(), ToByteSlice(), ToReader() and
+// ToChunkReader() at offset zero) cause data to be written into the
+// tee. Partial reads are passed through without being copied. When the
+// buffer is cloned, only the first of the returned buffers retains the
+// tee, so that the tee observes a single copy of the data.
+//
+// Errors returned by the tee are not propagated to the reader of the
+// buffer. They merely cause no further data to be written into the
+// tee. The tee is also not informed about data integrity errors. Data
+// that is written into it must therefore be validated separately.
+func NewTeeingBuffer(base Buffer, tee io.Writer) Buffer {
+	return &teeingBuffer{
+		base: base,
+		tee:  &teeWriter{w: tee},
+	}
+}
+
+func (b *teeingBuffer) decorateBuffer(replacement Buffer) Buffer {
+	return &teeingBuffer{
+		base: replacement,
+		tee:  b.tee,
+	}
+}
+
+func (b *teeingBuffer) GetSizeBytes() (int64, error) {
+	return b.base.GetSizeBytes()
+}
+
+func (b *teeingBuffer) GetDigest() (digest.Digest, bool) {
+	return b.base.GetDigest()
+}
+
+func (b *teeingBuffer) IntoWriter(w io.Writer) error {
+	return b.base.IntoWriter(&teeingWriter{
+		w:   w,
+		tee: b.tee,
+	})
+}
+
+func (b *teeingBuffer) ReadAt(p []byte, off int64) (int, error) {
+	return b.base.ReadAt(p, off)
+}
+
+func (b *teeingBuffer) ToProto(m proto.Message, maximumSizeBytes int) (proto.Message, error) {
+	return toProtoViaByteSlice(b, m, maximumSizeBytes)
+}
+
+func (b *teeingBuffer) ToByteSlice(maximumSizeBytes int) ([]byte, error) {
+	data, err := b.base.ToByteSlice(maximumSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	b.tee.write(data)
+	return data, nil
+}
+
+func (b *teeingBuffer) ToChunkReader(off int64, maximumChunkSizeBytes int) ChunkReader {
+	r := b.base.ToChunkReader(off, maximumChunkSizeBytes)
+	if off != 0 {
+		return r
+	}
+	return &teeingChunkReader{
+		r:   r,
+		tee: b.tee,
+	}
+}
+
+func (b *teeingBuffer) ToReader() io.ReadCloser {
+	return &teeingReader{
+		ReadCloser: b.base.ToReader(),
+		tee:        b.tee,
+	}
+}
+
+func (b *teeingBuffer) CloneCopy(maximumSizeBytes int) (Buffer, Buffer) {
+	b1, b2 := b.base.CloneCopy(maximumSizeBytes)
+	return b.decorateBuffer(b1), b2
+}
+
+func (b *teeingBuffer) CloneStream() (Buffer, Buffer) {
+	b1, b2 := b.base.CloneStream()
+	return b.decorateBuffer(b1), b2
+}
+
+func (b *teeingBuffer) Discard() {
+	b.base.Discard()
+}
+
+func (b *teeingBuffer) applyErrorHandler(errorHandler ErrorHandler) (Buffer, bool) {
+	replacement, shouldRetry := b.base.applyErrorHandler(errorHandler)
+	return b.decorateBuffer(replacement), shouldRetry
+}
+
+// The unvalidated readers are only used to concatenate parts of
+// buffers during error retrying. Data obtained through them may be
+// discarded, which is why it is not copied into the tee.
+
+func (b *teeingBuffer) toUnvalidatedChunkReader(off int64, maximumChunkSizeBytes int) ChunkReader {
+	return b.base.toUnvalidatedChunkReader(off, maximumChunkSizeBytes)
+}
+
+func (b *teeingBuffer) toUnvalidatedReader(off int64) io.ReadCloser {
+	return b.base.toUnvalidatedReader(off)
+}
+
+type teeingWriter struct {
+	w   io.Writer
+	tee *teeWriter
+}
+
+func (w *teeingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.tee.write(p[:n])
+	return n, err
+}
+
+type teeingChunkReader struct {
+	r   ChunkReader
+	tee *teeWriter
+}
+
+func (r *teeingChunkReader) Read() ([]byte, error) {
+	chunk, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.tee.write(chunk)
+	return chunk, nil
+}
+
+func (r *teeingChunkReader) Close() {
+	r.r.Close()
+}
+
+type teeingReader struct {
+	io.ReadCloser
+	tee *teeWriter
+}
+
+func (r *teeingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tee.write(p[:n])
+	return n, err
+}
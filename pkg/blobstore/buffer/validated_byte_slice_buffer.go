@@ -32,7 +32,7 @@ func NewCASBufferFromByteSlice(digest digest.Digest, data []byte, source Source)
 	expectedSizeBytes := digest.GetSizeBytes()
 	actualSizeBytes := int64(len(data))
 	if expectedSizeBytes != actualSizeBytes {
-		return NewBufferFromError(source.notifyCASSizeMismatch(expectedSizeBytes, actualSizeBytes))
+		return NewBufferFromError(source.notifyCASSizeMismatch(digest, expectedSizeBytes, actualSizeBytes))
 	}
 
 	// Compare the blob's checksum.
@@ -41,17 +41,24 @@ func NewCASBufferFromByteSlice(digest digest.Digest, data []byte, source Source)
 	hasher.Write(data)
 	actualChecksum := hasher.Sum(nil)
 	if bytes.Compare(expectedChecksum, actualChecksum) != 0 {
-		return NewBufferFromError(source.notifyCASHashMismatch(expectedChecksum, actualChecksum))
+		return NewBufferFromError(source.notifyCASHashMismatch(digest, expectedChecksum, actualChecksum))
 	}
 
 	source.notifyDataValid()
-	return NewValidatedBufferFromByteSlice(data)
+	return &casByteSliceBuffer{
+		validatedByteSliceBuffer: validatedByteSliceBuffer{data: data},
+		digest:                   digest,
+	}
 }
 
 func (b validatedByteSliceBuffer) GetSizeBytes() (int64, error) {
 	return int64(len(b.data)), nil
 }
 
+func (b validatedByteSliceBuffer) GetDigest() (digest.Digest, bool) {
+	return digest.BadDigest, false
+}
+
 func (b validatedByteSliceBuffer) IntoWriter(w io.Writer) error {
 	_, err := w.Write(b.data)
 	return err
@@ -77,7 +84,7 @@ func (b validatedByteSliceBuffer) ToProto(m proto.Message, maximumSizeBytes int)
 
 func (b validatedByteSliceBuffer) ToByteSlice(maximumSizeBytes int) ([]byte, error) {
 	if len(b.data) > maximumSizeBytes {
-		return nil, status.Errorf(codes.InvalidArgument, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", len(b.data), maximumSizeBytes)
+		return nil, newBufferTooLargeError(int64(len(b.data)), maximumSizeBytes)
 	}
 	return b.data, nil
 }
@@ -124,6 +131,33 @@ func (b validatedByteSliceBuffer) toUnvalidatedReader(off int64) io.ReadCloser {
 	return ioutil.NopCloser(bytes.NewBuffer(b.data[off:]))
 }
 
+// casByteSliceBuffer is a validatedByteSliceBuffer whose contents have
+// been validated against a digest. It keeps track of the digest, so
+// that it can be returned by GetDigest().
+type casByteSliceBuffer struct {
+	validatedByteSliceBuffer
+	digest digest.Digest
+}
+
+func (b *casByteSliceBuffer) GetDigest() (digest.Digest, bool) {
+	return b.digest, true
+}
+
+func (b *casByteSliceBuffer) CloneCopy(maximumSizeBytes int) (Buffer, Buffer) {
+	return b, b
+}
+
+func (b *casByteSliceBuffer) CloneStream() (Buffer, Buffer) {
+	return b, b
+}
+
+func (b *casByteSliceBuffer) applyErrorHandler(errorHandler ErrorHandler) (Buffer, bool) {
+	// The buffer is in a known good state. Terminate the error
+	// handler directly. There is no need to return a wrapped buffer.
+	errorHandler.Done()
+	return b, false
+}
+
 type byteSliceChunkReader struct {
 	maximumChunkSizeBytes int
 	data                  []byte
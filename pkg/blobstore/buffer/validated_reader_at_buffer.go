@@ -5,10 +5,8 @@ import (
 	"io/ioutil"
 	"sync/atomic"
 
+	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/golang/protobuf/proto"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 // ReadAtCloser is the stream type that is accepted by
@@ -48,6 +46,10 @@ func (b *validatedReaderBuffer) GetSizeBytes() (int64, error) {
 	return b.sizeBytes, nil
 }
 
+func (b *validatedReaderBuffer) GetDigest() (digest.Digest, bool) {
+	return digest.BadDigest, false
+}
+
 func (b *validatedReaderBuffer) IntoWriter(w io.Writer) error {
 	defer b.Discard()
 	_, err := io.Copy(w, io.NewSectionReader(b.r, 0, b.sizeBytes))
@@ -67,7 +69,7 @@ func (b *validatedReaderBuffer) ToByteSlice(maximumSizeBytes int) ([]byte, error
 	defer b.Discard()
 
 	if b.sizeBytes > int64(maximumSizeBytes) {
-		return nil, status.Errorf(codes.InvalidArgument, "Buffer is %d bytes in size, while a maximum of %d bytes is permitted", b.sizeBytes, maximumSizeBytes)
+		return nil, newBufferTooLargeError(b.sizeBytes, maximumSizeBytes)
 	}
 	return ioutil.ReadAll(io.NewSectionReader(b.r, 0, b.sizeBytes))
 }
@@ -3,6 +3,7 @@ package buffer
 import (
 	"io"
 
+	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -70,6 +71,10 @@ func (b *bufferWithBackgroundTask) GetSizeBytes() (int64, error) {
 	return b.base.GetSizeBytes()
 }
 
+func (b *bufferWithBackgroundTask) GetDigest() (digest.Digest, bool) {
+	return b.base.GetDigest()
+}
+
 func (b *bufferWithBackgroundTask) IntoWriter(w io.Writer) error {
 	err := b.base.IntoWriter(w)
 	<-b.task.completion
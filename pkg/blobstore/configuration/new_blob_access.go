@@ -205,10 +205,16 @@ func newNestedBlobAccessBare(configuration *pb.BlobAccessConfiguration, creator
 		if combinedDigestKeyFormat == nil {
 			return BlobAccessInfo{}, "", status.Errorf(codes.InvalidArgument, "Cannot create sharding blob access without any undrained backends")
 		}
+		var shardPermuter sharding.ShardPermuter
+		if backend.Sharding.UseRendezvousHashing {
+			shardPermuter = sharding.NewRendezvousShardPermuter(weights)
+		} else {
+			shardPermuter = sharding.NewWeightedShardPermuter(weights)
+		}
 		return BlobAccessInfo{
 			BlobAccess: sharding.NewShardingBlobAccess(
 				backends,
-				sharding.NewWeightedShardPermuter(weights),
+				shardPermuter,
 				backend.Sharding.HashInitialization),
 			DigestKeyFormat: *combinedDigestKeyFormat,
 		}, "sharding", nil
@@ -300,7 +306,8 @@ func newNestedBlobAccessBare(configuration *pb.BlobAccessConfiguration, creator
 				cachedReadBufferFactory,
 				sectorSizeBytes,
 				blockSectorCount,
-				int(blockCount))
+				int(blockCount),
+				blocksOnBlockDevice.DetectOverlappingWrites)
 		default:
 			return BlobAccessInfo{}, "", status.Error(codes.InvalidArgument, "Blocks backend not specified")
 		}
@@ -480,6 +487,15 @@ func newNestedBlobAccessBare(configuration *pb.BlobAccessConfiguration, creator
 				}),
 			DigestKeyFormat: digest.KeyWithInstance,
 		}, "demultiplexing", nil
+	case *pb.BlobAccessConfiguration_ErrorLogging:
+		base, err := NewNestedBlobAccess(backend.ErrorLogging, creator)
+		if err != nil {
+			return BlobAccessInfo{}, "", err
+		}
+		return BlobAccessInfo{
+			BlobAccess:      blobstore.NewErrorLoggingBlobAccess(base.BlobAccess, util.DefaultErrorLogger),
+			DigestKeyFormat: base.DigestKeyFormat,
+		}, "error_logging", nil
 	}
 	return creator.NewCustomBlobAccess(configuration)
 }
@@ -0,0 +1,68 @@
+package blobstore
+
+import (
+	"context"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+)
+
+type digestFunctionRoutingBlobAccess struct {
+	routes   map[remoteexecution.DigestFunction_Value]BlobAccess
+	fallback BlobAccess
+}
+
+// NewDigestFunctionRoutingBlobAccess creates a BlobAccess that splits
+// up requests between backends based on the digest function of the
+// object (e.g., SHA-256). This may be used to store objects using
+// legacy digest functions in a separate backend. Objects whose digest
+// function has no route are forwarded to a fallback backend.
+func NewDigestFunctionRoutingBlobAccess(routes map[remoteexecution.DigestFunction_Value]BlobAccess, fallback BlobAccess) BlobAccess {
+	return &digestFunctionRoutingBlobAccess{
+		routes:   routes,
+		fallback: fallback,
+	}
+}
+
+func (ba *digestFunctionRoutingBlobAccess) getBackend(digestFunction remoteexecution.DigestFunction_Value) BlobAccess {
+	if backend, ok := ba.routes[digestFunction]; ok {
+		return backend
+	}
+	return ba.fallback
+}
+
+func (ba *digestFunctionRoutingBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	return ba.getBackend(digest.GetDigestFunction()).Get(ctx, digest)
+}
+
+func (ba *digestFunctionRoutingBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	return ba.getBackend(digest.GetDigestFunction()).Put(ctx, digest, b)
+}
+
+func (ba *digestFunctionRoutingBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	// Partition the digest set by digest function.
+	partitions := map[remoteexecution.DigestFunction_Value]digest.SetBuilder{}
+	for _, blobDigest := range digests.Items() {
+		digestFunction := blobDigest.GetDigestFunction()
+		partition, ok := partitions[digestFunction]
+		if !ok {
+			partition = digest.NewSetBuilder()
+			partitions[digestFunction] = partition
+		}
+		partition.Add(blobDigest)
+	}
+
+	// Call FindMissing() on the backend of each of the partitions
+	// and gather the results into a single set.
+	allMissing := make([]digest.Set, 0, len(partitions))
+	for digestFunction, partition := range partitions {
+		missing, err := ba.getBackend(digestFunction).FindMissing(ctx, partition.Build())
+		if err != nil {
+			return digest.EmptySet, util.StatusWrapf(err, "Digest function %s", digestFunction)
+		}
+		allMissing = append(allMissing, missing)
+	}
+	return digest.GetUnion(allMissing), nil
+}
@@ -0,0 +1,89 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDigestFunctionRoutingBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	md5BlobAccess := mock.NewMockBlobAccess(ctrl)
+	sha256BlobAccess := mock.NewMockBlobAccess(ctrl)
+	fallbackBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewDigestFunctionRoutingBlobAccess(
+		map[remoteexecution.DigestFunction_Value]blobstore.BlobAccess{
+			remoteexecution.DigestFunction_MD5:    md5BlobAccess,
+			remoteexecution.DigestFunction_SHA256: sha256BlobAccess,
+		},
+		fallbackBlobAccess)
+
+	md5Digest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+	sha1Digest := digest.MustNewDigest("hello", "f7ff9e8b7bb2e09b70935a5d785e0cc5d9d0abf0", 5)
+	sha256Digest := digest.MustNewDigest("hello", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)
+
+	t.Run("Get", func(t *testing.T) {
+		md5BlobAccess.EXPECT().Get(ctx, md5Digest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+		data, err := blobAccess.Get(ctx, md5Digest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+
+		sha256BlobAccess.EXPECT().Get(ctx, sha256Digest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+		data, err = blobAccess.Get(ctx, sha256Digest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+
+		fallbackBlobAccess.EXPECT().Get(ctx, sha1Digest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+		_, err = blobAccess.Get(ctx, sha1Digest).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		md5BlobAccess.EXPECT().Put(ctx, md5Digest, gomock.Any()).Return(nil)
+		require.NoError(t, blobAccess.Put(ctx, md5Digest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+
+		sha256BlobAccess.EXPECT().Put(ctx, sha256Digest, gomock.Any()).Return(nil)
+		require.NoError(t, blobAccess.Put(ctx, sha256Digest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+
+	allDigests := digest.NewSetBuilder().Add(md5Digest).Add(sha1Digest).Add(sha256Digest).Build()
+
+	t.Run("FindMissingSuccess", func(t *testing.T) {
+		md5BlobAccess.EXPECT().FindMissing(ctx, md5Digest.ToSingletonSet()).
+			Return(md5Digest.ToSingletonSet(), nil)
+		sha256BlobAccess.EXPECT().FindMissing(ctx, sha256Digest.ToSingletonSet()).
+			Return(digest.EmptySet, nil)
+		fallbackBlobAccess.EXPECT().FindMissing(ctx, sha1Digest.ToSingletonSet()).
+			Return(sha1Digest.ToSingletonSet(), nil)
+
+		missing, err := blobAccess.FindMissing(ctx, allDigests)
+		require.NoError(t, err)
+		require.Equal(t, digest.NewSetBuilder().Add(md5Digest).Add(sha1Digest).Build(), missing)
+	})
+
+	t.Run("FindMissingFailure", func(t *testing.T) {
+		md5BlobAccess.EXPECT().FindMissing(ctx, md5Digest.ToSingletonSet()).
+			Return(digest.EmptySet, nil).AnyTimes()
+		sha256BlobAccess.EXPECT().FindMissing(ctx, sha256Digest.ToSingletonSet()).
+			Return(digest.EmptySet, status.Error(codes.Internal, "Server on fire"))
+		fallbackBlobAccess.EXPECT().FindMissing(ctx, sha1Digest.ToSingletonSet()).
+			Return(digest.EmptySet, nil).AnyTimes()
+
+		_, err := blobAccess.FindMissing(ctx, allDigests)
+		require.Equal(t, status.Error(codes.Internal, "Digest function SHA256: Server on fire"), err)
+	})
+}
@@ -0,0 +1,95 @@
+package blobstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DrainingBlobAccess is a BlobAccess that can be instructed to stop
+// accepting writes. It is returned by NewDrainingBlobAccess().
+type DrainingBlobAccess interface {
+	BlobAccess
+
+	// Drain causes all subsequent calls to Put() to fail. Calls
+	// to Get() and FindMissing() continue to be forwarded.
+	Drain()
+
+	// WaitForDrain blocks until all calls to Put() that were in
+	// flight at the time Drain() was called have completed. It
+	// calls Drain() implicitly.
+	WaitForDrain(ctx context.Context) error
+}
+
+type drainingBlobAccess struct {
+	BlobAccess
+
+	lock     sync.Mutex
+	draining bool
+	inFlight int
+	drained  chan struct{}
+}
+
+// NewDrainingBlobAccess creates a decorator for BlobAccess that can be
+// used to gracefully shut down a storage node. After Drain() is
+// called, new writes are rejected with codes.Unavailable, causing
+// clients to retry them against other nodes. Reads continue to be
+// served, so that the node's contents remain available until it is
+// actually terminated.
+func NewDrainingBlobAccess(base BlobAccess) DrainingBlobAccess {
+	return &drainingBlobAccess{
+		BlobAccess: base,
+		drained:    make(chan struct{}),
+	}
+}
+
+func (ba *drainingBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	ba.lock.Lock()
+	if ba.draining {
+		ba.lock.Unlock()
+		b.Discard()
+		return status.Error(codes.Unavailable, "Server draining")
+	}
+	ba.inFlight++
+	ba.lock.Unlock()
+
+	defer ba.putCompleted()
+	return ba.BlobAccess.Put(ctx, digest, b)
+}
+
+func (ba *drainingBlobAccess) putCompleted() {
+	ba.lock.Lock()
+	defer ba.lock.Unlock()
+	ba.inFlight--
+	if ba.draining && ba.inFlight == 0 {
+		close(ba.drained)
+	}
+}
+
+func (ba *drainingBlobAccess) Drain() {
+	ba.lock.Lock()
+	defer ba.lock.Unlock()
+	if !ba.draining {
+		ba.draining = true
+		if ba.inFlight == 0 {
+			close(ba.drained)
+		}
+	}
+}
+
+func (ba *drainingBlobAccess) WaitForDrain(ctx context.Context) error {
+	ba.Drain()
+
+	select {
+	case <-ba.drained:
+		return nil
+	case <-ctx.Done():
+		return util.StatusFromContext(ctx)
+	}
+}
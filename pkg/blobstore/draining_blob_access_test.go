@@ -0,0 +1,83 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDrainingBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewDrainingBlobAccess(baseBlobAccess)
+	blobDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	// Start a call to Put() that remains in flight until released.
+	putStarted := make(chan struct{})
+	putRelease := make(chan struct{})
+	baseBlobAccess.EXPECT().Put(ctx, blobDigest, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+			close(putStarted)
+			<-putRelease
+			_, err := b.ToByteSlice(100)
+			return err
+		})
+	putErr := make(chan error, 1)
+	go func() {
+		putErr <- blobAccess.Put(ctx, blobDigest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+	}()
+	<-putStarted
+
+	blobAccess.Drain()
+
+	t.Run("PutRejected", func(t *testing.T) {
+		require.Equal(
+			t,
+			status.Error(codes.Unavailable, "Server draining"),
+			blobAccess.Put(ctx, blobDigest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+
+	t.Run("GetForwarded", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+
+		data, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+
+	t.Run("FindMissingForwarded", func(t *testing.T) {
+		baseBlobAccess.EXPECT().FindMissing(ctx, blobDigest.ToSingletonSet()).
+			Return(digest.EmptySet, nil)
+
+		missing, err := blobAccess.FindMissing(ctx, blobDigest.ToSingletonSet())
+		require.NoError(t, err)
+		require.Equal(t, digest.EmptySet, missing)
+	})
+
+	t.Run("WaitForDrainTimeout", func(t *testing.T) {
+		// The call to Put() is still in flight, meaning that
+		// waiting should fail once the context is canceled.
+		ctxCanceled, cancel := context.WithCancel(ctx)
+		cancel()
+		require.Equal(t, codes.Canceled, status.Code(blobAccess.WaitForDrain(ctxCanceled)))
+	})
+
+	t.Run("WaitForDrainSuccess", func(t *testing.T) {
+		// Once the in-flight call to Put() completes, waiting
+		// should succeed.
+		close(putRelease)
+		require.NoError(t, blobAccess.WaitForDrain(ctx))
+		require.NoError(t, <-putErr)
+	})
+}
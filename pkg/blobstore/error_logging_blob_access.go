@@ -0,0 +1,95 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	bb_grpc "github.com/buildbarn/bb-storage/pkg/grpc"
+	"github.com/buildbarn/bb-storage/pkg/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type errorLoggingBlobAccess struct {
+	BlobAccess
+	errorLogger util.ErrorLogger
+}
+
+// NewErrorLoggingBlobAccess creates a decorator for BlobAccess that
+// reports errors returned by the backend to an ErrorLogger. Errors are
+// annotated with fields obtained from the REv2 RequestMetadata that is
+// attached to the Context, so that failures can be correlated with the
+// build invocations that caused them.
+//
+// NOT_FOUND errors are not reported, as these are part of regular
+// operation (e.g., Action Cache misses).
+func NewErrorLoggingBlobAccess(base BlobAccess, errorLogger util.ErrorLogger) BlobAccess {
+	return &errorLoggingBlobAccess{
+		BlobAccess:  base,
+		errorLogger: errorLogger,
+	}
+}
+
+// getRequestMetadataLogFields converts the REv2 RequestMetadata that is
+// attached to a Context to a string that can be appended to log
+// messages.
+func getRequestMetadataLogFields(ctx context.Context) string {
+	requestMetadata, ok := bb_grpc.RequestMetadataFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(
+		" [tool_name=%#v tool_invocation_id=%#v correlated_invocations_id=%#v action_id=%#v]",
+		requestMetadata.GetToolDetails().GetToolName(),
+		requestMetadata.GetToolInvocationId(),
+		requestMetadata.GetCorrelatedInvocationsId(),
+		requestMetadata.GetActionId())
+}
+
+func (ba *errorLoggingBlobAccess) logError(ctx context.Context, err error, format string, args ...interface{}) {
+	if status.Code(err) != codes.NotFound {
+		ba.errorLogger.Log(util.StatusWrapf(err, "%s%s", fmt.Sprintf(format, args...), getRequestMetadataLogFields(ctx)))
+	}
+}
+
+func (ba *errorLoggingBlobAccess) Get(ctx context.Context, blobDigest digest.Digest) buffer.Buffer {
+	return buffer.WithErrorHandler(
+		ba.BlobAccess.Get(ctx, blobDigest),
+		&errorLoggingErrorHandler{
+			blobAccess: ba,
+			ctx:        ctx,
+			blobDigest: blobDigest,
+		})
+}
+
+func (ba *errorLoggingBlobAccess) Put(ctx context.Context, blobDigest digest.Digest, b buffer.Buffer) error {
+	err := ba.BlobAccess.Put(ctx, blobDigest, b)
+	if err != nil {
+		ba.logError(ctx, err, "Failed to store blob %#v", blobDigest.String())
+	}
+	return err
+}
+
+func (ba *errorLoggingBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	missing, err := ba.BlobAccess.FindMissing(ctx, digests)
+	if err != nil {
+		ba.logError(ctx, err, "Failed to find missing blobs")
+	}
+	return missing, err
+}
+
+type errorLoggingErrorHandler struct {
+	blobAccess *errorLoggingBlobAccess
+	ctx        context.Context
+	blobDigest digest.Digest
+}
+
+func (eh *errorLoggingErrorHandler) OnError(err error) (buffer.Buffer, error) {
+	eh.blobAccess.logError(eh.ctx, err, "Failed to load blob %#v", eh.blobDigest.String())
+	return nil, err
+}
+
+func (eh *errorLoggingErrorHandler) Done() {}
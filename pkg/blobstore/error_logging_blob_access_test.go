@@ -0,0 +1,91 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	bb_grpc "github.com/buildbarn/bb-storage/pkg/grpc"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorLoggingBlobAccess(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	// Place MetricsBlobAccess in between, so that it can be
+	// observed that RequestMetadata flows through a chain of
+	// decorators.
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	clock := mock.NewMockClock(ctrl)
+	clock.EXPECT().Now().Return(time.Unix(1000, 0)).AnyTimes()
+	errorLogger := mock.NewMockErrorLogger(ctrl)
+	blobAccess := blobstore.NewErrorLoggingBlobAccess(
+		blobstore.NewMetricsBlobAccess(baseBlobAccess, clock, "error_logging_test"),
+		errorLogger)
+
+	requestMetadata := &remoteexecution.RequestMetadata{
+		ToolDetails: &remoteexecution.ToolDetails{
+			ToolName:    "bazel",
+			ToolVersion: "3.4.1",
+		},
+		ActionId:         "1b8ee8c3dd1e3d30d4c10b5a44a1a95e3a9f5e5e",
+		ToolInvocationId: "3c9a3d2e-0b0a-4c8e-9b4b-0f5a6e1c7d2b",
+	}
+	ctxWithRequestMetadata := bb_grpc.NewContextWithRequestMetadata(ctx, requestMetadata)
+	blobDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("GetWithRequestMetadata", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest) buffer.Buffer {
+				actualRequestMetadata, ok := bb_grpc.RequestMetadataFromContext(ctx)
+				require.True(t, ok)
+				require.True(t, proto.Equal(requestMetadata, actualRequestMetadata))
+				return buffer.NewBufferFromError(status.Error(codes.Unavailable, "Server offline"))
+			})
+		errorLogger.EXPECT().Log(status.Error(codes.Unavailable, "Failed to load blob \"8b1a9953c4611296a827abf8c47804d7-5-hello\" [tool_name=\"bazel\" tool_invocation_id=\"3c9a3d2e-0b0a-4c8e-9b4b-0f5a6e1c7d2b\" correlated_invocations_id=\"\" action_id=\"1b8ee8c3dd1e3d30d4c10b5a44a1a95e3a9f5e5e\"]: Server offline"))
+
+		_, err := blobAccess.Get(ctxWithRequestMetadata, blobDigest).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Unavailable, "Server offline"), err)
+	})
+
+	t.Run("PutWithoutRequestMetadata", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Put(ctx, blobDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				b.Discard()
+				return status.Error(codes.Internal, "Disk on fire")
+			})
+		errorLogger.EXPECT().Log(status.Error(codes.Internal, "Failed to store blob \"8b1a9953c4611296a827abf8c47804d7-5-hello\": Disk on fire"))
+
+		err := blobAccess.Put(ctx, blobDigest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+		require.Equal(t, status.Error(codes.Internal, "Disk on fire"), err)
+	})
+
+	t.Run("FindMissingNotFound", func(t *testing.T) {
+		// NOT_FOUND errors should not be logged.
+		digests := blobDigest.ToSingletonSet()
+		baseBlobAccess.EXPECT().FindMissing(ctxWithRequestMetadata, digests).
+			Return(digest.EmptySet, status.Error(codes.NotFound, "Instance name not found"))
+
+		_, err := blobAccess.FindMissing(ctxWithRequestMetadata, digests)
+		require.Equal(t, status.Error(codes.NotFound, "Instance name not found"), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctxWithRequestMetadata, blobDigest).
+			Return(buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+
+		data, err := blobAccess.Get(ctxWithRequestMetadata, blobDigest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+}
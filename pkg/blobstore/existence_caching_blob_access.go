@@ -22,6 +22,10 @@ type existenceCachingBlobAccess struct {
 // This decorator may be useful to run on instances that act as
 // frontends for a mirrored/sharding storage pool, as it may reduce the
 // load observed on the storage pool.
+//
+// Whether cached results are shared between instance names is
+// determined by the key format of the ExistenceCache. See
+// digest.NewExistenceCache() for details.
 func NewExistenceCachingBlobAccess(base BlobAccess, existenceCache *digest.ExistenceCache) BlobAccess {
 	return &existenceCachingBlobAccess{
 		BlobAccess:     base,
@@ -7,6 +7,7 @@ import (
 
 	"github.com/buildbarn/bb-storage/internal/mock"
 	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/clock"
 	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/buildbarn/bb-storage/pkg/eviction"
 	"github.com/golang/mock/gomock"
@@ -53,3 +54,48 @@ func TestExistenceCachingBlobAccessFindMissing(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, nonExistingDigests, missing)
 }
+
+func TestExistenceCachingBlobAccessKeyFormat(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	digestA := digest.MustNewDigest("a", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)
+	digestB := digest.MustNewDigest("b", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)
+
+	t.Run("WithInstance", func(t *testing.T) {
+		// The presence of an object in one instance should not
+		// cause it to be assumed present in another instance.
+		baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+		blobAccess := blobstore.NewExistenceCachingBlobAccess(
+			baseBlobAccess,
+			digest.NewExistenceCache(clock.NewDeterministicClock(time.Unix(1000, 0)), digest.KeyWithInstance, 10, time.Minute, eviction.NewLRUSet()))
+
+		baseBlobAccess.EXPECT().FindMissing(ctx, digestA.ToSingletonSet()).Return(digest.EmptySet, nil)
+		missing, err := blobAccess.FindMissing(ctx, digestA.ToSingletonSet())
+		require.NoError(t, err)
+		require.Equal(t, digest.EmptySet, missing)
+
+		baseBlobAccess.EXPECT().FindMissing(ctx, digestB.ToSingletonSet()).Return(digestB.ToSingletonSet(), nil)
+		missing, err = blobAccess.FindMissing(ctx, digestB.ToSingletonSet())
+		require.NoError(t, err)
+		require.Equal(t, digestB.ToSingletonSet(), missing)
+	})
+
+	t.Run("WithoutInstance", func(t *testing.T) {
+		// The presence of an object in one instance should be
+		// shared with all other instances.
+		baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+		blobAccess := blobstore.NewExistenceCachingBlobAccess(
+			baseBlobAccess,
+			digest.NewExistenceCache(clock.NewDeterministicClock(time.Unix(1000, 0)), digest.KeyWithoutInstance, 10, time.Minute, eviction.NewLRUSet()))
+
+		baseBlobAccess.EXPECT().FindMissing(ctx, digestA.ToSingletonSet()).Return(digest.EmptySet, nil)
+		missing, err := blobAccess.FindMissing(ctx, digestA.ToSingletonSet())
+		require.NoError(t, err)
+		require.Equal(t, digest.EmptySet, missing)
+
+		baseBlobAccess.EXPECT().FindMissing(ctx, digest.EmptySet).Return(digest.EmptySet, nil)
+		missing, err = blobAccess.FindMissing(ctx, digestB.ToSingletonSet())
+		require.NoError(t, err)
+		require.Equal(t, digest.EmptySet, missing)
+	})
+}
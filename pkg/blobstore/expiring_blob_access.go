@@ -0,0 +1,131 @@
+package blobstore
+
+import (
+	"context"
+	"time"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/clock"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/proto/blobstore/expiring"
+	"github.com/buildbarn/bb-storage/pkg/util"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type expiringBlobAccess struct {
+	BlobAccess
+	clock                           clock.Clock
+	ttl                             time.Duration
+	maximumMessageSizeBytes         int
+	untimestampedEntriesNeverExpire bool
+}
+
+// NewExpiringBlobAccess creates a decorator for an Action Cache (AC)
+// backed BlobAccess that causes entries to expire after a fixed amount
+// of time.
+//
+// ActionResult messages written through this decorator are stored in
+// the backend as ExpiringActionResult messages, containing the time at
+// which they were stored. Entries that are older than the configured
+// TTL are reported as being absent by Get() and FindMissing().
+//
+// Entries that have no timestamp (e.g., because they were written
+// before this decorator was put in place) are reported as being absent
+// as well, unless untimestampedEntriesNeverExpire is set. In that case
+// they are returned as is, and never expire.
+//
+// As the backend has no knowledge of the age of entries, FindMissing()
+// needs to load every entry that the backend reports as being present.
+// These entries are loaded sequentially, meaning that the cost of
+// FindMissing() is proportional to the number of digests provided.
+// This is acceptable for the Action Cache, as clients rarely call
+// FindMissing() against it with large sets of digests.
+func NewExpiringBlobAccess(base BlobAccess, clock clock.Clock, ttl time.Duration, maximumMessageSizeBytes int, untimestampedEntriesNeverExpire bool) BlobAccess {
+	return &expiringBlobAccess{
+		BlobAccess:                      base,
+		clock:                           clock,
+		ttl:                             ttl,
+		maximumMessageSizeBytes:         maximumMessageSizeBytes,
+		untimestampedEntriesNeverExpire: untimestampedEntriesNeverExpire,
+	}
+}
+
+func (ba *expiringBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	// Keep a copy of the buffer returned by the backend, so that it
+	// can be returned as is for entries that have no timestamp.
+	b1, b2 := ba.BlobAccess.Get(ctx, digest).CloneCopy(ba.maximumMessageSizeBytes)
+	data, err := b1.ToByteSlice(ba.maximumMessageSizeBytes)
+	if err != nil {
+		b2.Discard()
+		return buffer.NewBufferFromError(err)
+	}
+
+	var expiringActionResult expiring.ExpiringActionResult
+	if err := proto.Unmarshal(data, &expiringActionResult); err != nil || expiringActionResult.ActionResult == nil {
+		// The entry is a plain ActionResult message that has
+		// no timestamp. Return the backend's buffer directly,
+		// so that data integrity errors are reported to the
+		// backend.
+		if ba.untimestampedEntriesNeverExpire {
+			return b2
+		}
+		b2.Discard()
+		return buffer.NewBufferFromError(status.Error(codes.NotFound, "Action result has no timestamp"))
+	}
+	b2.Discard()
+
+	storedAt, err := ptypes.Timestamp(expiringActionResult.StoredAt)
+	if err != nil {
+		return buffer.NewBufferFromError(util.StatusWrapWithCode(err, codes.Internal, "Invalid stored-at timestamp"))
+	}
+	if expiresAt := storedAt.Add(ba.ttl); !ba.clock.Now().Before(expiresAt) {
+		return buffer.NewBufferFromError(status.Errorf(codes.NotFound, "Action result expired at %s", expiresAt.UTC().Format(time.RFC3339)))
+	}
+	return buffer.NewProtoBufferFromProto(expiringActionResult.ActionResult, buffer.BackendProvided(buffer.Irreparable(digest)))
+}
+
+func (ba *expiringBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	actionResult, err := b.ToProto(&remoteexecution.ActionResult{}, ba.maximumMessageSizeBytes)
+	if err != nil {
+		return err
+	}
+	storedAt, err := ptypes.TimestampProto(ba.clock.Now())
+	if err != nil {
+		return util.StatusWrapWithCode(err, codes.Internal, "Failed to create timestamp")
+	}
+	return ba.BlobAccess.Put(
+		ctx,
+		digest,
+		buffer.NewProtoBufferFromProto(
+			&expiring.ExpiringActionResult{
+				ActionResult: actionResult.(*remoteexecution.ActionResult),
+				StoredAt:     storedAt,
+			},
+			buffer.UserProvided))
+}
+
+func (ba *expiringBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	missing, err := ba.BlobAccess.FindMissing(ctx, digests)
+	if err != nil {
+		return digest.EmptySet, err
+	}
+
+	// The backend has no knowledge of the age of entries. Load the
+	// entries that are present to determine whether they expired.
+	present, _, _ := digest.GetDifferenceAndIntersection(digests, missing)
+	expired := digest.NewSetBuilder()
+	for _, blobDigest := range present.Items() {
+		if _, err := ba.Get(ctx, blobDigest).ToProto(&remoteexecution.ActionResult{}, ba.maximumMessageSizeBytes); err != nil {
+			if status.Code(err) != codes.NotFound {
+				return digest.EmptySet, util.StatusWrapf(err, "Failed to load action result %#v", blobDigest.String())
+			}
+			expired.Add(blobDigest)
+		}
+	}
+	return digest.GetUnion([]digest.Set{missing, expired.Build()}), nil
+}
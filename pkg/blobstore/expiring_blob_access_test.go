@@ -0,0 +1,182 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/clock"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/proto/blobstore/expiring"
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExpiringBlobAccessGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	clock := clock.NewDeterministicClock(time.Unix(1000, 0))
+	blobAccess := blobstore.NewExpiringBlobAccess(baseBlobAccess, clock, time.Hour, 1000, false)
+	actionDigest := digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 123)
+	actionResult := &remoteexecution.ActionResult{
+		ExitCode: 42,
+	}
+	expiringActionResult := &expiring.ExpiringActionResult{
+		ActionResult: actionResult,
+		StoredAt:     &timestamp.Timestamp{Seconds: 1000},
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("NoTimestamp", func(t *testing.T) {
+		// By default, entries without a timestamp should be
+		// reported as being absent.
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided))
+
+		_, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.Equal(t, status.Error(codes.NotFound, "Action result has no timestamp"), err)
+	})
+
+	t.Run("NoTimestampNeverExpires", func(t *testing.T) {
+		// If configured, entries without a timestamp should
+		// be returned as is.
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided))
+
+		m, err := blobstore.NewExpiringBlobAccess(baseBlobAccess, clock, time.Hour, 1000, true).
+			Get(ctx, actionDigest).
+			ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(actionResult, m))
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		// Right before the TTL is reached, the entry should
+		// still be returned.
+		clock.Advance(time.Hour - time.Second)
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromProto(expiringActionResult, buffer.UserProvided))
+
+		m, err := blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(actionResult, m))
+
+		// Once the TTL is reached, it should be reported as
+		// being absent.
+		clock.Advance(time.Second)
+		baseBlobAccess.EXPECT().Get(ctx, actionDigest).Return(
+			buffer.NewProtoBufferFromProto(expiringActionResult, buffer.UserProvided))
+
+		_, err = blobAccess.Get(ctx, actionDigest).ToProto(&remoteexecution.ActionResult{}, 1000)
+		require.Equal(t, status.Error(codes.NotFound, "Action result expired at 1970-01-01T01:16:40Z"), err)
+	})
+}
+
+func TestExpiringBlobAccessPut(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	clock := clock.NewDeterministicClock(time.Unix(1000, 0))
+	blobAccess := blobstore.NewExpiringBlobAccess(baseBlobAccess, clock, time.Hour, 1000, false)
+	actionDigest := digest.MustNewDigest("hello", "d41d8cd98f00b204e9800998ecf8427e", 123)
+
+	t.Run("Stamped", func(t *testing.T) {
+		// Entries should be stored together with the current
+		// time. Timestamps provided by the worker should not be
+		// used for this purpose.
+		actionResult := &remoteexecution.ActionResult{
+			ExitCode: 42,
+			ExecutionMetadata: &remoteexecution.ExecutedActionMetadata{
+				WorkerCompletedTimestamp: &timestamp.Timestamp{Seconds: 900},
+			},
+		}
+		baseBlobAccess.EXPECT().Put(ctx, actionDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				m, err := b.ToProto(&expiring.ExpiringActionResult{}, 1000)
+				require.NoError(t, err)
+				require.True(t, proto.Equal(&expiring.ExpiringActionResult{
+					ActionResult: actionResult,
+					StoredAt:     &timestamp.Timestamp{Seconds: 1000},
+				}, m))
+				return nil
+			})
+
+		require.NoError(t, blobAccess.Put(ctx, actionDigest, buffer.NewProtoBufferFromProto(actionResult, buffer.UserProvided)))
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		// Data that cannot be unmarshaled should be rejected.
+		err := blobAccess.Put(ctx, actionDigest, buffer.NewProtoBufferFromByteSlice(&remoteexecution.ActionResult{}, []byte{0xff}, buffer.UserProvided))
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestExpiringBlobAccessFindMissing(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	clock := clock.NewDeterministicClock(time.Unix(1000, 0))
+	blobAccess := blobstore.NewExpiringBlobAccess(baseBlobAccess, clock, time.Hour, 1000, false)
+	digestFresh := digest.MustNewDigest("hello", "00000000000000000000000000000001", 123)
+	digestExpired := digest.MustNewDigest("hello", "00000000000000000000000000000002", 123)
+	digestMissing := digest.MustNewDigest("hello", "00000000000000000000000000000003", 123)
+	allDigests := digest.NewSetBuilder().Add(digestFresh).Add(digestExpired).Add(digestMissing).Build()
+
+	clock.Advance(time.Hour)
+
+	t.Run("BackendFailure", func(t *testing.T) {
+		baseBlobAccess.EXPECT().FindMissing(ctx, allDigests).
+			Return(digest.EmptySet, status.Error(codes.Unavailable, "Server offline"))
+
+		_, err := blobAccess.FindMissing(ctx, allDigests)
+		require.Equal(t, status.Error(codes.Unavailable, "Server offline"), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		// Entries that are present, but have expired, should
+		// also be reported as missing.
+		baseBlobAccess.EXPECT().FindMissing(ctx, allDigests).
+			Return(digestMissing.ToSingletonSet(), nil)
+		baseBlobAccess.EXPECT().Get(ctx, digestFresh).Return(
+			buffer.NewProtoBufferFromProto(&expiring.ExpiringActionResult{
+				ActionResult: &remoteexecution.ActionResult{},
+				StoredAt:     &timestamp.Timestamp{Seconds: 1001},
+			}, buffer.UserProvided))
+		baseBlobAccess.EXPECT().Get(ctx, digestExpired).Return(
+			buffer.NewProtoBufferFromProto(&expiring.ExpiringActionResult{
+				ActionResult: &remoteexecution.ActionResult{},
+				StoredAt:     &timestamp.Timestamp{Seconds: 1000},
+			}, buffer.UserProvided))
+
+		missing, err := blobAccess.FindMissing(ctx, allDigests)
+		require.NoError(t, err)
+		require.Equal(t, digest.NewSetBuilder().Add(digestExpired).Add(digestMissing).Build(), missing)
+	})
+
+	t.Run("GetFailure", func(t *testing.T) {
+		baseBlobAccess.EXPECT().FindMissing(ctx, allDigests).
+			Return(digest.NewSetBuilder().Add(digestExpired).Add(digestMissing).Build(), nil)
+		baseBlobAccess.EXPECT().Get(ctx, digestFresh).Return(
+			buffer.NewBufferFromError(status.Error(codes.Internal, "Disk on fire")))
+
+		_, err := blobAccess.FindMissing(ctx, allDigests)
+		require.Equal(t, status.Error(codes.Internal, "Failed to load action result \"00000000000000000000000000000001-123-hello\": Disk on fire"), err)
+	})
+}
@@ -0,0 +1,60 @@
+package blobstore
+
+import (
+	"context"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fallbackEmptyBlobAccess struct {
+	BlobAccess
+	allow func(digest.Digest) bool
+}
+
+// NewFallbackEmptyBlobAccess is a decorator for BlobAccess that causes
+// Get() to return an empty buffer in case the backend reports that the
+// empty blob is absent. This is only done for digests for which the
+// provided function returns true.
+//
+// This decorator may be used as part of recovering from data loss, to
+// let builds proceed while storage is being repopulated. Unlike
+// NewEmptyBlobInjectingBlobAccess(), the backend is still consulted.
+// Blobs whose digest does not correspond to that of the empty blob are
+// left untouched, as returning fabricated contents for them would
+// cause corruption.
+func NewFallbackEmptyBlobAccess(base BlobAccess, allow func(digest.Digest) bool) BlobAccess {
+	return &fallbackEmptyBlobAccess{
+		BlobAccess: base,
+		allow:      allow,
+	}
+}
+
+func (ba *fallbackEmptyBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	return buffer.WithErrorHandler(
+		ba.BlobAccess.Get(ctx, digest),
+		&fallbackEmptyErrorHandler{
+			allow:  ba.allow,
+			digest: digest,
+		})
+}
+
+type fallbackEmptyErrorHandler struct {
+	allow  func(digest.Digest) bool
+	digest digest.Digest
+}
+
+func (eh *fallbackEmptyErrorHandler) OnError(err error) (buffer.Buffer, error) {
+	if status.Code(err) == codes.NotFound && eh.allow(eh.digest) {
+		emptyDigest, emptyErr := eh.digest.GetInstanceName().NewEmptyDigest(eh.digest.GetDigestFunction())
+		if emptyErr == nil && eh.digest == emptyDigest {
+			return buffer.NewValidatedBufferFromByteSlice(nil), nil
+		}
+	}
+	return nil, err
+}
+
+func (eh *fallbackEmptyErrorHandler) Done() {}
@@ -0,0 +1,93 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFallbackEmptyBlobAccessGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewFallbackEmptyBlobAccess(
+		baseBlobAccess,
+		func(blobDigest digest.Digest) bool {
+			return blobDigest.GetInstanceName().String() == "allowed"
+		})
+
+	t.Run("Success", func(t *testing.T) {
+		// Blobs present in the backend should be returned as is.
+		blobDigest := digest.MustNewDigest("allowed", "7fc56270e7a70fa81a5935b72eacbe29", 1)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("A")))
+
+		data, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.NoError(t, err)
+		require.Equal(t, []byte("A"), data)
+	})
+
+	t.Run("EmptyNotFound", func(t *testing.T) {
+		// Absence of the empty blob should be masked.
+		blobDigest := digest.MustNewDigest("allowed", "d41d8cd98f00b204e9800998ecf8427e", 0)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		data, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.NoError(t, err)
+		require.Empty(t, data)
+	})
+
+	t.Run("EmptyNotAllowed", func(t *testing.T) {
+		// Only digests permitted by the function should be
+		// considered.
+		blobDigest := digest.MustNewDigest("disallowed", "d41d8cd98f00b204e9800998ecf8427e", 0)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("EmptyFailure", func(t *testing.T) {
+		// Errors other than NotFound should be propagated.
+		blobDigest := digest.MustNewDigest("allowed", "d41d8cd98f00b204e9800998ecf8427e", 0)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.Internal, "Server on fire")))
+
+		_, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.Equal(t, status.Error(codes.Internal, "Server on fire"), err)
+	})
+
+	t.Run("NonEmptyNotFound", func(t *testing.T) {
+		// Contents of non-empty blobs cannot be fabricated, so
+		// NotFound should be preserved.
+		blobDigest := digest.MustNewDigest("allowed", "7fc56270e7a70fa81a5935b72eacbe29", 1)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("ZeroSizeWrongHash", func(t *testing.T) {
+		// Digests with a size of zero, but a hash that doesn't
+		// correspond to the empty blob are invalid. These
+		// should not be masked either.
+		blobDigest := digest.MustNewDigest("allowed", "7fc56270e7a70fa81a5935b72eacbe29", 0)
+		baseBlobAccess.EXPECT().Get(ctx, blobDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(1)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+}
@@ -0,0 +1,29 @@
+package blobstore
+
+import (
+	"context"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+)
+
+// GetAndRehash is a helper function for reading a blob from the Content
+// Addressable Storage (CAS), while computing its digest using another
+// digest function. The data is validated against the source digest.
+// This may be used to build cross references between digests when
+// migrating from one digest function to another.
+func GetAndRehash(ctx context.Context, blobAccess BlobAccess, sourceDigest digest.Digest, targetDigestFunction remoteexecution.DigestFunction_Value, maximumSizeBytes int) ([]byte, digest.Digest, error) {
+	digestGenerator, err := sourceDigest.GetInstanceName().NewGenerator(targetDigestFunction)
+	if err != nil {
+		return nil, digest.BadDigest, util.StatusWrap(err, "Failed to create target digest generator")
+	}
+	data, err := blobAccess.Get(ctx, sourceDigest).ToByteSlice(maximumSizeBytes)
+	if err != nil {
+		return nil, digest.BadDigest, err
+	}
+	if _, err := digestGenerator.Write(data); err != nil {
+		panic(err)
+	}
+	return data, digestGenerator.Sum(), nil
+}
@@ -0,0 +1,56 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetAndRehash(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	blobAccess := mock.NewMockBlobAccess(ctrl)
+	sourceDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("UnsupportedDigestFunction", func(t *testing.T) {
+		_, _, err := blobstore.GetAndRehash(ctx, blobAccess, sourceDigest, remoteexecution.DigestFunction_VSO, 100)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Failed to create target digest generator: Unsupported digest function: VSO"), err)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		blobAccess.EXPECT().Get(ctx, sourceDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, _, err := blobstore.GetAndRehash(ctx, blobAccess, sourceDigest, remoteexecution.DigestFunction_SHA256, 100)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+
+	t.Run("SourceChecksumMismatch", func(t *testing.T) {
+		// Data should be validated against the source digest.
+		blobAccess.EXPECT().Get(ctx, sourceDigest).Return(
+			buffer.NewCASBufferFromByteSlice(sourceDigest, []byte("Hallo"), buffer.UserProvided))
+
+		_, _, err := blobstore.GetAndRehash(ctx, blobAccess, sourceDigest, remoteexecution.DigestFunction_SHA256, 100)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Buffer has checksum d1bf93299de1b68e6d382c893bf1215f, while 8b1a9953c4611296a827abf8c47804d7 was expected"), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		blobAccess.EXPECT().Get(ctx, sourceDigest).Return(
+			buffer.NewCASBufferFromByteSlice(sourceDigest, []byte("Hello"), buffer.UserProvided))
+
+		data, targetDigest, err := blobstore.GetAndRehash(ctx, blobAccess, sourceDigest, remoteexecution.DigestFunction_SHA256, 100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+		require.Equal(t, digest.MustNewDigest("hello", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5), targetDigest)
+	})
+}
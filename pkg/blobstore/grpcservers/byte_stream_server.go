@@ -32,12 +32,15 @@ func (s *byteStreamServer) Read(in *bytestream.ReadRequest, out bytestream.ByteS
 	if in.ReadLimit != 0 {
 		return status.Error(codes.Unimplemented, "This service does not support downloading partial files")
 	}
-	digest, err := digest.NewDigestFromByteStreamReadPath(in.ResourceName)
+	blobDigest, compressor, err := digest.NewDigestFromByteStreamReadPath(in.ResourceName)
 	if err != nil {
 		return err
 	}
+	if compressor != digest.CompressorIdentity {
+		return status.Errorf(codes.Unimplemented, "This service does not support downloading %s compressed files", compressor)
+	}
 
-	r := s.blobAccess.Get(out.Context(), digest).ToChunkReader(in.ReadOffset, s.readChunkSize)
+	r := s.blobAccess.Get(out.Context(), blobDigest).ToChunkReader(in.ReadOffset, s.readChunkSize)
 	defer r.Close()
 
 	for {
@@ -102,22 +105,25 @@ func (s *byteStreamServer) Write(stream bytestream.ByteStream_WriteServer) error
 	if err != nil {
 		return err
 	}
-	digest, err := digest.NewDigestFromByteStreamWritePath(request.ResourceName)
+	blobDigest, compressor, err := digest.NewDigestFromByteStreamWritePath(request.ResourceName)
 	if err != nil {
 		return err
 	}
+	if compressor != digest.CompressorIdentity {
+		return status.Errorf(codes.Unimplemented, "This service does not support uploading %s compressed files", compressor)
+	}
 	r := &byteStreamWriteServerChunkReader{stream: stream}
 	if err := r.setRequest(request); err != nil {
 		return err
 	}
 	if err := s.blobAccess.Put(
 		stream.Context(),
-		digest,
-		buffer.NewCASBufferFromChunkReader(digest, r, buffer.UserProvided)); err != nil {
+		blobDigest,
+		buffer.NewCASBufferFromChunkReader(blobDigest, r, buffer.UserProvided)); err != nil {
 		return err
 	}
 	return stream.SendAndClose(&bytestream.WriteResponse{
-		CommittedSize: digest.GetSizeBytes(),
+		CommittedSize: blobDigest.GetSizeBytes(),
 	})
 }
 
@@ -50,6 +50,17 @@ func TestByteStreamServer(t *testing.T) {
 		require.Equal(t, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"), err)
 	})
 
+	t.Run("ReadCompressed", func(t *testing.T) {
+		// Compressed transfers are recognized, but not
+		// supported by this implementation.
+		req, err := client.Read(ctx, &bytestream.ReadRequest{
+			ResourceName: "compressed-blobs/zstd/09f7e02f1290be211da707a266f153b3/5",
+		})
+		require.NoError(t, err)
+		_, err = req.Recv()
+		require.Equal(t, status.Error(codes.Unimplemented, "This service does not support downloading zstd compressed files"), err)
+	})
+
 	t.Run("ReadInvalidDigestLength", func(t *testing.T) {
 		// Invalid digest length.
 		req, err := client.Read(ctx, &bytestream.ReadRequest{
@@ -204,6 +215,17 @@ func TestByteStreamServer(t *testing.T) {
 		require.Equal(t, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"), err)
 	})
 
+	t.Run("WriteCompressed", func(t *testing.T) {
+		stream, err := client.Write(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&bytestream.WriteRequest{
+			ResourceName: "uploads/da2f1135-326b-4956-b920-1646cdd6cb53/compressed-blobs/zstd/09f7e02f1290be211da707a266f153b3/5",
+			Data:         []byte("Bleep bloop!"),
+		}))
+		_, err = stream.CloseAndRecv()
+		require.Equal(t, status.Error(codes.Unimplemented, "This service does not support uploading zstd compressed files"), err)
+	})
+
 	t.Run("WriteSuccessEmptyInstance", func(t *testing.T) {
 		// Attempt to write a blob without an instance name.
 		blobAccess.EXPECT().Put(
@@ -0,0 +1,71 @@
+package blobstore
+
+import (
+	"context"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type instanceNameFilteringBlobAccess struct {
+	BlobAccess
+	allowedInstanceName digest.InstanceNameMatcher
+}
+
+// NewInstanceNameFilteringBlobAccess is a decorator for BlobAccess that
+// only permits access to storage for certain instance names. Unlike
+// NewInstanceNameAccessCheckingBlobAccess(), it restricts all
+// operations, not just writes. This can be used in multi-tenant setups
+// to limit which instance names may be accessed through a given
+// frontend.
+//
+// FindMissing() calls that contain one or more digests with an
+// instance name that is not permitted are rejected entirely. Filtering
+// those digests would cause them to be reported as present.
+func NewInstanceNameFilteringBlobAccess(base BlobAccess, allowedInstanceName digest.InstanceNameMatcher) BlobAccess {
+	return &instanceNameFilteringBlobAccess{
+		BlobAccess:          base,
+		allowedInstanceName: allowedInstanceName,
+	}
+}
+
+func (ba *instanceNameFilteringBlobAccess) checkInstanceName(instanceName digest.InstanceName) error {
+	if !ba.allowedInstanceName(instanceName) {
+		return status.Errorf(codes.PermissionDenied, "This service does not permit access to instance name %#v", instanceName.String())
+	}
+	return nil
+}
+
+func (ba *instanceNameFilteringBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	if err := ba.checkInstanceName(digest.GetInstanceName()); err != nil {
+		return buffer.NewBufferFromError(err)
+	}
+	return ba.BlobAccess.Get(ctx, digest)
+}
+
+func (ba *instanceNameFilteringBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	if err := ba.checkInstanceName(digest.GetInstanceName()); err != nil {
+		b.Discard()
+		return err
+	}
+	return ba.BlobAccess.Put(ctx, digest, b)
+}
+
+func (ba *instanceNameFilteringBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	// Only check every instance name once, as sets tend to
+	// contain many digests with the same instance name.
+	checkedInstanceNames := map[digest.InstanceName]struct{}{}
+	for _, blobDigest := range digests.Items() {
+		instanceName := blobDigest.GetInstanceName()
+		if _, ok := checkedInstanceNames[instanceName]; !ok {
+			if err := ba.checkInstanceName(instanceName); err != nil {
+				return digest.EmptySet, err
+			}
+			checkedInstanceNames[instanceName] = struct{}{}
+		}
+	}
+	return ba.BlobAccess.FindMissing(ctx, digests)
+}
@@ -0,0 +1,115 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInstanceNameFilteringBlobAccessGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	instanceNameMatcher := mock.NewMockInstanceNameMatcher(ctrl)
+	blobAccess := blobstore.NewInstanceNameFilteringBlobAccess(baseBlobAccess, instanceNameMatcher.Call)
+
+	t.Run("PermissionDenied", func(t *testing.T) {
+		// The backend should not be contacted.
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("denied")).Return(false)
+
+		_, err := blobAccess.Get(ctx, digest.MustNewDigest("denied", "8b1a9953c4611296a827abf8c47804d7", 5)).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.PermissionDenied, "This service does not permit access to instance name \"denied\""), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		helloDigest := digest.MustNewDigest("allowed", "8b1a9953c4611296a827abf8c47804d7", 5)
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("allowed")).Return(true)
+		baseBlobAccess.EXPECT().Get(ctx, helloDigest).Return(buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+
+		data, err := blobAccess.Get(ctx, helloDigest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+}
+
+func TestInstanceNameFilteringBlobAccessPut(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	instanceNameMatcher := mock.NewMockInstanceNameMatcher(ctrl)
+	blobAccess := blobstore.NewInstanceNameFilteringBlobAccess(baseBlobAccess, instanceNameMatcher.Call)
+
+	t.Run("PermissionDenied", func(t *testing.T) {
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("denied")).Return(false)
+
+		require.Equal(
+			t,
+			status.Error(codes.PermissionDenied, "This service does not permit access to instance name \"denied\""),
+			blobAccess.Put(
+				ctx,
+				digest.MustNewDigest("denied", "8b1a9953c4611296a827abf8c47804d7", 5),
+				buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		helloDigest := digest.MustNewDigest("allowed", "8b1a9953c4611296a827abf8c47804d7", 5)
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("allowed")).Return(true)
+		baseBlobAccess.EXPECT().Put(ctx, helloDigest, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+				data, err := b.ToByteSlice(100)
+				require.NoError(t, err)
+				require.Equal(t, []byte("Hello"), data)
+				return nil
+			})
+
+		require.NoError(
+			t,
+			blobAccess.Put(ctx, helloDigest, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+}
+
+func TestInstanceNameFilteringBlobAccessFindMissing(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	instanceNameMatcher := mock.NewMockInstanceNameMatcher(ctrl)
+	blobAccess := blobstore.NewInstanceNameFilteringBlobAccess(baseBlobAccess, instanceNameMatcher.Call)
+
+	t.Run("PermissionDenied", func(t *testing.T) {
+		// A single digest with a disallowed instance name
+		// should cause the entire call to be rejected.
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("allowed")).Return(true)
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("denied")).Return(false)
+
+		_, err := blobAccess.FindMissing(
+			ctx,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("allowed", "6fc422233a40a75a1f028e11c3cd1140", 7)).
+				Add(digest.MustNewDigest("denied", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Build())
+		require.Equal(t, status.Error(codes.PermissionDenied, "This service does not permit access to instance name \"denied\""), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		// Every instance name should only be checked once.
+		digests := digest.NewSetBuilder().
+			Add(digest.MustNewDigest("allowed", "8b1a9953c4611296a827abf8c47804d7", 5)).
+			Add(digest.MustNewDigest("allowed", "6fc422233a40a75a1f028e11c3cd1140", 7)).
+			Build()
+		instanceNameMatcher.EXPECT().Call(digest.MustNewInstanceName("allowed")).Return(true)
+		baseBlobAccess.EXPECT().FindMissing(ctx, digests).Return(digests, nil)
+
+		missing, err := blobAccess.FindMissing(ctx, digests)
+		require.NoError(t, err)
+		require.Equal(t, digests, missing)
+	})
+}
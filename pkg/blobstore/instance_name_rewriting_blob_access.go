@@ -0,0 +1,88 @@
+package blobstore
+
+import (
+	"context"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+)
+
+type instanceNameRewritingBlobAccess struct {
+	base    BlobAccess
+	rewrite func(string) string
+}
+
+// NewInstanceNameRewritingBlobAccess creates a decorator for BlobAccess
+// that changes the instance name of all digests that are forwarded to
+// the backend. The hash and size of digests are left intact. This may
+// be used to forward requests to backends that use different instance
+// naming conventions.
+//
+// Unlike NewDemultiplexingBlobAccess(), which can only substitute
+// prefixes of instance names, arbitrary rewrites may be performed. The
+// rewrite function does not need to be invertible. Digests returned by
+// FindMissing() are translated back to the digests that were provided
+// by the caller.
+func NewInstanceNameRewritingBlobAccess(base BlobAccess, rewrite func(string) string) BlobAccess {
+	return &instanceNameRewritingBlobAccess{
+		base:    base,
+		rewrite: rewrite,
+	}
+}
+
+func (ba *instanceNameRewritingBlobAccess) rewriteDigest(blobDigest digest.Digest) (digest.Digest, error) {
+	oldInstanceName := blobDigest.GetInstanceName().String()
+	rewrittenDigest, err := blobDigest.WithInstance(ba.rewrite(oldInstanceName))
+	if err != nil {
+		return digest.BadDigest, util.StatusWrapf(err, "Failed to rewrite instance name %#v", oldInstanceName)
+	}
+	return rewrittenDigest, nil
+}
+
+func (ba *instanceNameRewritingBlobAccess) Get(ctx context.Context, blobDigest digest.Digest) buffer.Buffer {
+	rewrittenDigest, err := ba.rewriteDigest(blobDigest)
+	if err != nil {
+		return buffer.NewBufferFromError(err)
+	}
+	return ba.base.Get(ctx, rewrittenDigest)
+}
+
+func (ba *instanceNameRewritingBlobAccess) Put(ctx context.Context, blobDigest digest.Digest, b buffer.Buffer) error {
+	rewrittenDigest, err := ba.rewriteDigest(blobDigest)
+	if err != nil {
+		b.Discard()
+		return err
+	}
+	return ba.base.Put(ctx, rewrittenDigest, b)
+}
+
+func (ba *instanceNameRewritingBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	// Rewrite all digests, while keeping track of which digests
+	// they originated from. Multiple digests may be rewritten to
+	// the same digest.
+	originalDigests := map[digest.Digest][]digest.Digest{}
+	rewrittenDigests := digest.NewSetBuilder()
+	for _, blobDigest := range digests.Items() {
+		rewrittenDigest, err := ba.rewriteDigest(blobDigest)
+		if err != nil {
+			return digest.EmptySet, err
+		}
+		originalDigests[rewrittenDigest] = append(originalDigests[rewrittenDigest], blobDigest)
+		rewrittenDigests.Add(rewrittenDigest)
+	}
+
+	rewrittenMissing, err := ba.base.FindMissing(ctx, rewrittenDigests.Build())
+	if err != nil {
+		return digest.EmptySet, err
+	}
+
+	// Translate the results back to the original digests.
+	missing := digest.NewSetBuilder()
+	for _, rewrittenDigest := range rewrittenMissing.Items() {
+		for _, blobDigest := range originalDigests[rewrittenDigest] {
+			missing.Add(blobDigest)
+		}
+	}
+	return missing.Build(), nil
+}
@@ -0,0 +1,129 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newInstanceNameRewritingBlobAccessForTesting(ctrl *gomock.Controller) (*mock.MockBlobAccess, blobstore.BlobAccess) {
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewInstanceNameRewritingBlobAccess(
+		baseBlobAccess,
+		func(instanceName string) string {
+			switch instanceName {
+			case "a", "b":
+				return "shared"
+			case "invalid":
+				return "/invalid"
+			default:
+				return instanceName
+			}
+		})
+	return baseBlobAccess, blobAccess
+}
+
+func TestInstanceNameRewritingBlobAccessGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess, blobAccess := newInstanceNameRewritingBlobAccessForTesting(ctrl)
+
+	t.Run("InvalidInstanceName", func(t *testing.T) {
+		_, err := blobAccess.Get(ctx, digest.MustNewDigest("invalid", "8b1a9953c4611296a827abf8c47804d7", 5)).ToByteSlice(100)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Failed to rewrite instance name \"invalid\": Instance name contains redundant slashes"), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Get(ctx, digest.MustNewDigest("shared", "8b1a9953c4611296a827abf8c47804d7", 5)).
+			Return(buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+
+		data, err := blobAccess.Get(ctx, digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5)).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+}
+
+func TestInstanceNameRewritingBlobAccessPut(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess, blobAccess := newInstanceNameRewritingBlobAccessForTesting(ctrl)
+
+	t.Run("InvalidInstanceName", func(t *testing.T) {
+		require.Equal(
+			t,
+			status.Error(codes.InvalidArgument, "Failed to rewrite instance name \"invalid\": Instance name contains redundant slashes"),
+			blobAccess.Put(ctx, digest.MustNewDigest("invalid", "8b1a9953c4611296a827abf8c47804d7", 5), buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		baseBlobAccess.EXPECT().Put(ctx, digest.MustNewDigest("shared", "8b1a9953c4611296a827abf8c47804d7", 5), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, blobDigest digest.Digest, b buffer.Buffer) error {
+				data, err := b.ToByteSlice(100)
+				require.NoError(t, err)
+				require.Equal(t, []byte("Hello"), data)
+				return nil
+			})
+
+		require.NoError(t, blobAccess.Put(ctx, digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5), buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+	})
+}
+
+func TestInstanceNameRewritingBlobAccessFindMissing(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess, blobAccess := newInstanceNameRewritingBlobAccessForTesting(ctrl)
+
+	t.Run("BackendFailure", func(t *testing.T) {
+		baseBlobAccess.EXPECT().FindMissing(ctx, digest.MustNewDigest("c", "8b1a9953c4611296a827abf8c47804d7", 5).ToSingletonSet()).
+			Return(digest.EmptySet, status.Error(codes.Internal, "Server on fire"))
+
+		_, err := blobAccess.FindMissing(ctx, digest.MustNewDigest("c", "8b1a9953c4611296a827abf8c47804d7", 5).ToSingletonSet())
+		require.Equal(t, status.Error(codes.Internal, "Server on fire"), err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		// Digests that are rewritten to the same digest should
+		// only be requested once, but should all be reported
+		// as missing.
+		baseBlobAccess.EXPECT().FindMissing(
+			ctx,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("c", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("c", "d41d8cd98f00b204e9800998ecf8427e", 0)).
+				Add(digest.MustNewDigest("shared", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Build(),
+		).Return(
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("c", "d41d8cd98f00b204e9800998ecf8427e", 0)).
+				Add(digest.MustNewDigest("shared", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Build(),
+			nil)
+
+		missing, err := blobAccess.FindMissing(
+			ctx,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("c", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("c", "d41d8cd98f00b204e9800998ecf8427e", 0)).
+				Build())
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("c", "d41d8cd98f00b204e9800998ecf8427e", 0)).
+				Build(),
+			missing)
+	})
+}
@@ -3,6 +3,7 @@ package local
 import (
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -51,12 +52,64 @@ var (
 )
 
 type blockDeviceBackedBlockAllocator struct {
-	blockDevice       blockdevice.BlockDevice
-	readBufferFactory blobstore.ReadBufferFactory
-	sectorSizeBytes   int
+	blockDevice             blockdevice.BlockDevice
+	readBufferFactory       blobstore.ReadBufferFactory
+	sectorSizeBytes         int
+	blockSectorCount        int64
+	blockCount              int
+	detectOverlappingWrites bool
+
+	lock             sync.Mutex
+	freeOffsets      []int64
+	allocatedBlocks  map[int64]*blockDeviceBackedBlock
+	allocationCounts map[int64]uint64
+}
+
+// BlockDeviceBackedBlockAllocator is a BlockAllocator that stores
+// blocks on a BlockDevice. In addition to the operations provided by
+// BlockAllocator, it allows inspecting the state of its blocks.
+type BlockDeviceBackedBlockAllocator interface {
+	BlockAllocator
+
+	// GetSnapshot returns the state of all blocks managed by the
+	// allocator. The snapshot is taken atomically.
+	GetSnapshot() BlockDeviceBackedBlockAllocatorSnapshot
+
+	// GetAllocationCounts returns the number of times each block,
+	// identified by its offset, has been allocated. This can be
+	// used to monitor whether wear is distributed evenly across
+	// the underlying storage.
+	GetAllocationCounts() map[int64]uint64
+
+	// BlockCount returns the number of blocks managed by the
+	// allocator.
+	BlockCount() int
+
+	// GetBlockOffsets returns the offsets of all blocks managed by
+	// the allocator in increasing order. These are the only
+	// offsets that are accepted by NewBlockAtOffset().
+	GetBlockOffsets() []int64
+
+	// Grow the number of blocks managed by the allocator. The new
+	// blocks are placed at offsets following the existing ones.
+	// The caller must ensure that the BlockDevice is large enough
+	// to hold them.
+	Grow(additionalBlocks int) error
+}
 
-	lock        sync.Mutex
-	freeOffsets []int64
+// BlockDeviceBackedBlockAllocatorSnapshot contains the state of all
+// blocks managed by a BlockDeviceBackedBlockAllocator at a given point
+// in time. Blocks are identified by their offset.
+type BlockDeviceBackedBlockAllocatorSnapshot struct {
+	// Blocks that are not in use, in the order in which they are
+	// handed out by NewBlock(). This corresponds to the order in
+	// which they were released.
+	FreeOffsets []int64
+	// Blocks that have been allocated and not yet been released.
+	AllocatedOffsets []int64
+	// Blocks that have been released, but cannot be reused yet,
+	// because buffers returned by Get() still refer to them.
+	PinnedOffsets []int64
 }
 
 // NewBlockDeviceBackedBlockAllocator implements a BlockAllocator that
@@ -72,7 +125,22 @@ type blockDeviceBackedBlockAllocator struct {
 // This implementation also ensures that writes against underlying
 // storage are all performed at sector boundaries and sizes. This
 // ensures that no unnecessary reads are performed.
-func NewBlockDeviceBackedBlockAllocator(blockDevice blockdevice.BlockDevice, readBufferFactory blobstore.ReadBufferFactory, sectorSizeBytes int, blockSectorCount int64, blockCount int) BlockAllocator {
+//
+// Buffers returned by Block.Get() are created through the provided
+// ReadBufferFactory. By default, these validate the entire blob, even
+// if only a part of it is accessed through ReadAt(). Efficient random
+// access can be obtained by wrapping the ReadBufferFactory using
+// NewValidationCachingReadBufferFactory(). Blobs are then only
+// validated the first time they are accessed, at the cost of not
+// detecting corruption that occurs afterwards.
+//
+// When detectOverlappingWrites is set, the allocator keeps track of the
+// regions of every block that have been written. Calls to Put() that
+// overlap with data written previously cause a panic, after discarding
+// the buffer that was provided. This is useful
+// when developing implementations of BlockList, but adds overhead to
+// every write.
+func NewBlockDeviceBackedBlockAllocator(blockDevice blockdevice.BlockDevice, readBufferFactory blobstore.ReadBufferFactory, sectorSizeBytes int, blockSectorCount int64, blockCount int, detectOverlappingWrites bool) BlockDeviceBackedBlockAllocator {
 	blockDeviceBackedBlockAllocatorPrometheusMetrics.Do(func() {
 		prometheus.MustRegister(blockDeviceBackedBlockAllocatorAllocations)
 		prometheus.MustRegister(blockDeviceBackedBlockAllocatorReleases)
@@ -82,23 +150,33 @@ func NewBlockDeviceBackedBlockAllocator(blockDevice blockdevice.BlockDevice, rea
 	})
 
 	pa := &blockDeviceBackedBlockAllocator{
-		blockDevice:       blockDevice,
-		readBufferFactory: readBufferFactory,
-		sectorSizeBytes:   sectorSizeBytes,
+		blockDevice:             blockDevice,
+		readBufferFactory:       readBufferFactory,
+		sectorSizeBytes:         sectorSizeBytes,
+		blockSectorCount:        blockSectorCount,
+		blockCount:              blockCount,
+		detectOverlappingWrites: detectOverlappingWrites,
+		allocatedBlocks:         map[int64]*blockDeviceBackedBlock{},
+		allocationCounts:        map[int64]uint64{},
 	}
 	for i := 0; i < blockCount; i++ {
-		pa.freeOffsets = append(pa.freeOffsets, int64(i)*blockSectorCount)
+		offset := int64(i) * blockSectorCount
+		pa.freeOffsets = append(pa.freeOffsets, offset)
+		pa.allocationCounts[offset] = 0
 	}
 	return pa
 }
 
 func (pa *blockDeviceBackedBlockAllocator) newBlockObject(offset int64) Block {
 	blockDeviceBackedBlockAllocatorAllocations.Inc()
-	return &blockDeviceBackedBlock{
+	pb := &blockDeviceBackedBlock{
 		blockAllocator: pa,
 		offset:         offset,
 		usecount:       1,
 	}
+	pa.allocatedBlocks[offset] = pb
+	pa.allocationCounts[offset]++
+	return pb
 }
 
 func (pa *blockDeviceBackedBlockAllocator) NewBlock() (Block, int64, error) {
@@ -127,13 +205,156 @@ func (pa *blockDeviceBackedBlockAllocator) NewBlockAtOffset(desiredOffset int64)
 	return nil, false
 }
 
+func (pa *blockDeviceBackedBlockAllocator) GetSnapshot() BlockDeviceBackedBlockAllocatorSnapshot {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+
+	snapshot := BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets: append([]int64(nil), pa.freeOffsets...),
+	}
+	for offset, pb := range pa.allocatedBlocks {
+		if pb.releasedByOwner {
+			snapshot.PinnedOffsets = append(snapshot.PinnedOffsets, offset)
+		} else {
+			snapshot.AllocatedOffsets = append(snapshot.AllocatedOffsets, offset)
+		}
+	}
+	sort.Slice(snapshot.AllocatedOffsets, func(i, j int) bool {
+		return snapshot.AllocatedOffsets[i] < snapshot.AllocatedOffsets[j]
+	})
+	sort.Slice(snapshot.PinnedOffsets, func(i, j int) bool {
+		return snapshot.PinnedOffsets[i] < snapshot.PinnedOffsets[j]
+	})
+	return snapshot
+}
+
+func (pa *blockDeviceBackedBlockAllocator) GetAllocationCounts() map[int64]uint64 {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+
+	allocationCounts := make(map[int64]uint64, len(pa.allocationCounts))
+	for offset, count := range pa.allocationCounts {
+		allocationCounts[offset] = count
+	}
+	return allocationCounts
+}
+
+func (pa *blockDeviceBackedBlockAllocator) BlockCount() int {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+
+	return pa.blockCount
+}
+
+func (pa *blockDeviceBackedBlockAllocator) GetBlockOffsets() []int64 {
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+
+	offsets := make([]int64, 0, pa.blockCount)
+	for i := 0; i < pa.blockCount; i++ {
+		offsets = append(offsets, int64(i)*pa.blockSectorCount)
+	}
+	return offsets
+}
+
+func (pa *blockDeviceBackedBlockAllocator) Grow(additionalBlocks int) error {
+	if additionalBlocks < 0 {
+		return status.Errorf(codes.InvalidArgument, "Cannot grow allocator by a negative number of blocks: %d", additionalBlocks)
+	}
+
+	pa.lock.Lock()
+	defer pa.lock.Unlock()
+
+	// The new blocks have never been used. Place them at the front
+	// of the free list, as they are less worn than any of the
+	// blocks that have been released.
+	newOffsets := make([]int64, 0, additionalBlocks+len(pa.freeOffsets))
+	for i := pa.blockCount; i < pa.blockCount+additionalBlocks; i++ {
+		offset := int64(i) * pa.blockSectorCount
+		newOffsets = append(newOffsets, offset)
+		pa.allocationCounts[offset] = 0
+	}
+	pa.freeOffsets = append(newOffsets, pa.freeOffsets...)
+	pa.blockCount += additionalBlocks
+	return nil
+}
+
 type blockDeviceBackedBlock struct {
 	blockAllocator *blockDeviceBackedBlockAllocator
 	offset         int64
 	usecount       int64
+
+	// Whether Release() has been called by the owner of the block.
+	// Protected by the allocator's lock.
+	releasedByOwner bool
+
+	// Regions of the block that have been written, only tracked if
+	// detectOverlappingWrites is set.
+	writtenRegionsLock sync.Mutex
+	writtenRegions     []blockDeviceBackedBlockRegion
+}
+
+// blockDeviceBackedBlockRegion is a byte range [start, end) within a
+// block that has been written by a call to Put().
+type blockDeviceBackedBlockRegion struct {
+	start int64
+	end   int64
+}
+
+// registerWrite records that a region of the block is about to be
+// written. It returns an error if the region overlaps with one that has
+// been written previously. The region is recorded before the write is
+// performed, so that concurrent overlapping writes are detected as
+// well.
+func (pb *blockDeviceBackedBlock) registerWrite(offsetBytes int64, sizeBytes int64) (blockDeviceBackedBlockRegion, error) {
+	// Writes are padded to sector boundaries. Account for this, as
+	// the padding would overwrite data as well.
+	sectorSizeBytes := int64(pb.blockAllocator.sectorSizeBytes)
+	region := blockDeviceBackedBlockRegion{
+		start: offsetBytes,
+		end:   offsetBytes + (sizeBytes+sectorSizeBytes-1)/sectorSizeBytes*sectorSizeBytes,
+	}
+	if region.start == region.end {
+		return region, nil
+	}
+
+	pb.writtenRegionsLock.Lock()
+	defer pb.writtenRegionsLock.Unlock()
+
+	for _, existing := range pb.writtenRegions {
+		if region.start < existing.end && existing.start < region.end {
+			return region, fmt.Errorf("Put(): Write to bytes [%d, %d) overlaps with earlier write to bytes [%d, %d)", region.start, region.end, existing.start, existing.end)
+		}
+	}
+	pb.writtenRegions = append(pb.writtenRegions, region)
+	return region, nil
+}
+
+// unregisterWrite removes a region of the block that was recorded by
+// registerWrite, because writing it failed. This permits the region to
+// be written once again.
+func (pb *blockDeviceBackedBlock) unregisterWrite(region blockDeviceBackedBlockRegion) {
+	pb.writtenRegionsLock.Lock()
+	defer pb.writtenRegionsLock.Unlock()
+
+	for i, existing := range pb.writtenRegions {
+		if existing == region {
+			pb.writtenRegions = append(pb.writtenRegions[:i], pb.writtenRegions[i+1:]...)
+			return
+		}
+	}
 }
 
 func (pb *blockDeviceBackedBlock) Release() {
+	pa := pb.blockAllocator
+	pa.lock.Lock()
+	pb.releasedByOwner = true
+	pa.lock.Unlock()
+
+	pb.decreaseUsecount()
+}
+
+func (pb *blockDeviceBackedBlock) decreaseUsecount() {
 	if c := atomic.AddInt64(&pb.usecount, -1); c < 0 {
 		panic(fmt.Sprintf("Release(): Block has invalid reference count %d", c))
 	} else if c == 0 {
@@ -141,6 +362,7 @@ func (pb *blockDeviceBackedBlock) Release() {
 		// storage to be reused for new data.
 		pa := pb.blockAllocator
 		pa.lock.Lock()
+		delete(pa.allocatedBlocks, pb.offset)
 		pa.freeOffsets = append(pa.freeOffsets, pb.offset)
 		pa.lock.Unlock()
 		blockDeviceBackedBlockAllocatorReleases.Inc()
@@ -176,9 +398,31 @@ func (pb *blockDeviceBackedBlock) Put(offsetBytes int64, b buffer.Buffer) error
 		panic("Attempted to store buffer at unaligned location")
 	}
 
+	if pb.blockAllocator.detectOverlappingWrites {
+		sizeBytes, err := b.GetSizeBytes()
+		if err != nil {
+			b.Discard()
+			return err
+		}
+		region, err := pb.registerWrite(offsetBytes, sizeBytes)
+		if err != nil {
+			b.Discard()
+			panic(err.Error())
+		}
+		if err := pb.write(offsetBytes, b); err != nil {
+			pb.unregisterWrite(region)
+			return err
+		}
+		return nil
+	}
+	return pb.write(offsetBytes, b)
+}
+
+func (pb *blockDeviceBackedBlock) write(offsetBytes int64, b buffer.Buffer) error {
+	sectorSizeBytes := pb.blockAllocator.sectorSizeBytes
 	w := &blockDeviceBackedBlockWriter{
 		w:             pb.blockAllocator.blockDevice,
-		partialSector: make([]byte, 0, pb.blockAllocator.sectorSizeBytes),
+		partialSector: make([]byte, 0, sectorSizeBytes),
 		offset:        pb.offset + offsetBytes/int64(sectorSizeBytes),
 	}
 
@@ -197,7 +441,7 @@ type blockDeviceBackedBlockReader struct {
 }
 
 func (r *blockDeviceBackedBlockReader) Close() error {
-	r.block.Release()
+	r.block.decreaseUsecount()
 	r.block = nil
 	blockDeviceBackedBlockAllocatorGetsCompleted.Inc()
 	return nil
@@ -2,12 +2,15 @@ package local_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/buildbarn/bb-storage/internal/mock"
 	"github.com/buildbarn/bb-storage/pkg/blobstore"
 	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
 	"github.com/buildbarn/bb-storage/pkg/blobstore/local"
+	"github.com/buildbarn/bb-storage/pkg/clock"
 	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/eviction"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 
@@ -19,7 +22,7 @@ func TestBlockDeviceBackedBlockAllocator(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
 	blockDevice := mock.NewMockBlockDevice(ctrl)
-	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 10)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 10, false)
 
 	// Based on the size of the allocator, it should be possible to
 	// create ten blocks.
@@ -106,3 +109,250 @@ func TestBlockDeviceBackedBlockAllocator(t *testing.T) {
 	blockDevice.EXPECT().WriteAt([]byte("Hello"), int64(741)).Return(5, nil)
 	require.NoError(t, blocks[7].Put(41, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
 }
+
+func TestBlockDeviceBackedBlockAllocatorOverlappingWrites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 4, 100, 10, true)
+	block, offset, err := pa.NewBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), offset)
+
+	// Writes are padded to sector boundaries. The first write thus
+	// occupies bytes [0, 8).
+	blockDevice.EXPECT().WriteAt([]byte("Hell"), int64(0)).Return(4, nil)
+	blockDevice.EXPECT().WriteAt([]byte("o\x00\x00\x00"), int64(4)).Return(4, nil)
+	require.NoError(t, block.Put(0, buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))))
+
+	// Writes that are adjacent should be permitted.
+	blockDevice.EXPECT().WriteAt([]byte("Goodbye!"), int64(8)).Return(8, nil)
+	require.NoError(t, block.Put(8, buffer.NewValidatedBufferFromByteSlice([]byte("Goodbye!"))))
+
+	// Regions of failed writes should not be recorded, as that
+	// would cause subsequent attempts to write them to panic.
+	blockDevice.EXPECT().WriteAt([]byte("Hello, world"), int64(16)).Return(0, status.Error(codes.Internal, "Disk on fire"))
+	require.Equal(
+		t,
+		status.Error(codes.Internal, "Disk on fire"),
+		block.Put(16, buffer.NewValidatedBufferFromByteSlice([]byte("Hello, world"))))
+	blockDevice.EXPECT().WriteAt([]byte("Hello, world"), int64(16)).Return(12, nil)
+	require.NoError(t, block.Put(16, buffer.NewValidatedBufferFromByteSlice([]byte("Hello, world"))))
+
+	// Writes that overlap with the padding of earlier writes should
+	// be detected. The buffer should be discarded before panicking.
+	reader := mock.NewMockReadAtCloser(ctrl)
+	reader.EXPECT().Close()
+	require.PanicsWithValue(t, "Put(): Write to bytes [4, 8) overlaps with earlier write to bytes [0, 8)", func() {
+		block.Put(4, buffer.NewValidatedBufferFromReaderAt(reader, 2))
+	})
+
+	// Once the block is released and allocated once again, the
+	// regions that were written previously should be forgotten.
+	block.Release()
+	block, found := pa.NewBlockAtOffset(0)
+	require.True(t, found)
+	blockDevice.EXPECT().WriteAt([]byte("Hey!"), int64(4)).Return(4, nil)
+	require.NoError(t, block.Put(4, buffer.NewValidatedBufferFromByteSlice([]byte("Hey!"))))
+}
+
+func TestBlockDeviceBackedBlockAllocatorValidationCaching(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	// Let the allocator create buffers that only validate the
+	// contents of a blob the first time it is accessed.
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(
+		blockDevice,
+		blobstore.NewValidationCachingReadBufferFactory(
+			blobstore.CASReadBufferFactory,
+			digest.NewExistenceCache(
+				clock.NewDeterministicClock(time.Unix(1000, 0)),
+				digest.KeyWithoutInstance,
+				10,
+				time.Minute,
+				eviction.NewLRUSet())),
+		1,
+		100,
+		10,
+		false)
+	block, offset, err := pa.NewBlock()
+	require.NoError(t, err)
+	require.Equal(t, int64(0), offset)
+	helloDigest := digest.MustNewDigest("some-instance", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	// The first call to ReadAt() needs to read the entire blob, so
+	// that its checksum can be validated.
+	blockDevice.EXPECT().ReadAt(gomock.Any(), int64(10)).DoAndReturn(
+		func(p []byte, off int64) (int, error) {
+			return copy(p, "Hello"), nil
+		})
+	dataIntegrityCallback1 := mock.NewMockDataIntegrityCallback(ctrl)
+	dataIntegrityCallback1.EXPECT().Call(true)
+	var p [5]byte
+	n, err := block.Get(helloDigest, 10, 5, dataIntegrityCallback1.Call).ReadAt(p[:], 0)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, []byte("Hello"), p[:])
+
+	// Successive calls to ReadAt() should only read the part of
+	// the blob that is requested, without validating it again.
+	for i := 0; i < 3; i++ {
+		blockDevice.EXPECT().ReadAt(gomock.Len(2), int64(11)).DoAndReturn(
+			func(p []byte, off int64) (int, error) {
+				return copy(p, "el"), nil
+			})
+		dataIntegrityCallback2 := mock.NewMockDataIntegrityCallback(ctrl)
+		n, err := block.Get(helloDigest, 10, 5, dataIntegrityCallback2.Call).ReadAt(p[:2], 1)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		require.Equal(t, []byte("el"), p[:2])
+	}
+}
+
+func TestBlockDeviceBackedBlockAllocatorGetSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 5, false)
+
+	// Initially, all blocks should be free.
+	require.Equal(t, local.BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets: []int64{0, 100, 200, 300, 400},
+	}, pa.GetSnapshot())
+
+	var blocks []local.Block
+	for i := 0; i < 3; i++ {
+		block, _, err := pa.NewBlock()
+		require.NoError(t, err)
+		blocks = append(blocks, block)
+	}
+	require.Equal(t, local.BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets:      []int64{300, 400},
+		AllocatedOffsets: []int64{0, 100, 200},
+	}, pa.GetSnapshot())
+
+	// Blocks that are released while buffers still refer to them
+	// should be reported as pinned. Blocks that are released
+	// without any buffers referring to them become free
+	// immediately.
+	b := blocks[1].Get(
+		digest.MustNewDigest("some-instance", "8b1a9953c4611296a827abf8c47804d7", 5),
+		0,
+		5,
+		func(dataIsValid bool) {})
+	blocks[1].Release()
+	blocks[2].Release()
+	require.Equal(t, local.BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets:      []int64{300, 400, 200},
+		AllocatedOffsets: []int64{0},
+		PinnedOffsets:    []int64{100},
+	}, pa.GetSnapshot())
+
+	// Once the buffer is discarded, the pinned block should become
+	// free as well.
+	b.Discard()
+	require.Equal(t, local.BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets:      []int64{300, 400, 200, 100},
+		AllocatedOffsets: []int64{0},
+	}, pa.GetSnapshot())
+}
+
+func TestBlockDeviceBackedBlockAllocatorGetAllocationCounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 3, false)
+
+	// Initially, no blocks have been allocated.
+	require.Equal(t, map[int64]uint64{0: 0, 100: 0, 200: 0}, pa.GetAllocationCounts())
+
+	// Repeatedly allocating and releasing a single block should
+	// cause the blocks to be used in a round-robin fashion.
+	for i := 0; i < 4; i++ {
+		block, _, err := pa.NewBlock()
+		require.NoError(t, err)
+		block.Release()
+	}
+	require.Equal(t, map[int64]uint64{0: 2, 100: 1, 200: 1}, pa.GetAllocationCounts())
+
+	// Allocations at an explicit offset should be counted as well.
+	block, found := pa.NewBlockAtOffset(200)
+	require.True(t, found)
+	require.Equal(t, map[int64]uint64{0: 2, 100: 1, 200: 2}, pa.GetAllocationCounts())
+	block.Release()
+}
+
+func TestBlockDeviceBackedBlockAllocatorBlockOffsets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 5, false)
+
+	require.Equal(t, 5, pa.BlockCount())
+	require.Equal(t, []int64{0, 100, 200, 300, 400}, pa.GetBlockOffsets())
+
+	t.Run("Unaligned", func(t *testing.T) {
+		_, found := pa.NewBlockAtOffset(150)
+		require.False(t, found)
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		_, found := pa.NewBlockAtOffset(-100)
+		require.False(t, found)
+	})
+
+	t.Run("BeyondDevice", func(t *testing.T) {
+		_, found := pa.NewBlockAtOffset(500)
+		require.False(t, found)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		block, found := pa.NewBlockAtOffset(400)
+		require.True(t, found)
+		block.Release()
+	})
+}
+
+func TestBlockDeviceBackedBlockAllocatorGrow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	blockDevice := mock.NewMockBlockDevice(ctrl)
+	pa := local.NewBlockDeviceBackedBlockAllocator(blockDevice, blobstore.CASReadBufferFactory, 1, 100, 3, false)
+
+	t.Run("Shrink", func(t *testing.T) {
+		require.Equal(
+			t,
+			status.Error(codes.InvalidArgument, "Cannot grow allocator by a negative number of blocks: -1"),
+			pa.Grow(-1))
+	})
+
+	// Allocate all blocks.
+	var blocks []local.Block
+	for i := 0; i < 3; i++ {
+		block, _, err := pa.NewBlock()
+		require.NoError(t, err)
+		blocks = append(blocks, block)
+	}
+	_, _, err := pa.NewBlock()
+	require.Equal(t, status.Error(codes.ResourceExhausted, "No unused blocks available"), err)
+
+	// After growing the allocator, new blocks should be handed out
+	// at offsets following the existing ones. Existing allocations
+	// should be left intact.
+	require.NoError(t, pa.Grow(5))
+	require.Equal(t, 8, pa.BlockCount())
+	require.Equal(t, local.BlockDeviceBackedBlockAllocatorSnapshot{
+		FreeOffsets:      []int64{300, 400, 500, 600, 700},
+		AllocatedOffsets: []int64{0, 100, 200},
+	}, pa.GetSnapshot())
+
+	// Blocks that are released should only be reused after all of
+	// the new blocks have been handed out.
+	blocks[0].Release()
+	for _, expectedOffset := range []int64{300, 400, 500, 600, 700, 0} {
+		_, offset, err := pa.NewBlock()
+		require.NoError(t, err)
+		require.Equal(t, expectedOffset, offset)
+	}
+}
@@ -0,0 +1,49 @@
+package local
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+)
+
+// ScrubbedBlob describes the location of a single blob stored in a
+// Block that should be checked by ScrubBlock().
+type ScrubbedBlob struct {
+	Digest                digest.Digest
+	OffsetBytes           int64
+	SizeBytes             int64
+	DataIntegrityCallback buffer.DataIntegrityCallback
+}
+
+// ScrubBlock reads a series of blobs stored in a Block and validates
+// their contents against their digests. This can be used to
+// proactively detect data corruption on the underlying storage medium,
+// as opposed to only detecting it when blobs are requested by clients.
+//
+// Blobs are always validated as if they were stored in the Content
+// Addressable Storage (CAS), regardless of the ReadBufferFactory that
+// is used by the Block. The DataIntegrityCallback of each blob is
+// invoked with the outcome of validation, so that corrupted blobs may
+// be repaired or evicted. Blocks that perform validation themselves
+// may invoke the DataIntegrityCallback more than once.
+//
+// All blobs are processed, even if some of them fail validation. The
+// first error that is encountered is returned.
+func ScrubBlock(block Block, blobs []ScrubbedBlob) error {
+	var firstErr error
+	for _, blob := range blobs {
+		r := buffer.NewCASBufferFromReader(
+			blob.Digest,
+			block.Get(blob.Digest, blob.OffsetBytes, blob.SizeBytes, blob.DataIntegrityCallback).ToReader(),
+			buffer.BackendProvided(blob.DataIntegrityCallback)).ToReader()
+		_, err := io.Copy(ioutil.Discard, r)
+		r.Close()
+		if err != nil && firstErr == nil {
+			firstErr = util.StatusWrapf(err, "Failed to scrub blob %#v", blob.Digest.String())
+		}
+	}
+	return firstErr
+}
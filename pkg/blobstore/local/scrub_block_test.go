@@ -0,0 +1,49 @@
+package local_test
+
+import (
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/local"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestScrubBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	block, _, err := local.NewInMemoryBlockAllocator(1024).NewBlock()
+	require.NoError(t, err)
+
+	// Seed the block with two blobs, where the second one has been
+	// corrupted.
+	require.NoError(t, block.Put(0, buffer.NewValidatedBufferFromByteSlice([]byte("HelloWorlD"))))
+
+	dataIntegrityCallback1 := mock.NewMockDataIntegrityCallback(ctrl)
+	dataIntegrityCallback1.EXPECT().Call(true)
+	dataIntegrityCallback2 := mock.NewMockDataIntegrityCallback(ctrl)
+	dataIntegrityCallback2.EXPECT().Call(false)
+
+	require.Equal(
+		t,
+		status.Error(codes.Internal, "Failed to scrub blob \"f5a7924e621e84c9280a9a27e1bcb7f6-5-hello\": Buffer has checksum 71be8838e6f943a39af12e7736ae181c, while f5a7924e621e84c9280a9a27e1bcb7f6 was expected"),
+		local.ScrubBlock(block, []local.ScrubbedBlob{
+			{
+				Digest:                digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5),
+				OffsetBytes:           0,
+				SizeBytes:             5,
+				DataIntegrityCallback: dataIntegrityCallback1.Call,
+			},
+			{
+				Digest:                digest.MustNewDigest("hello", "f5a7924e621e84c9280a9a27e1bcb7f6", 5),
+				OffsetBytes:           5,
+				SizeBytes:             5,
+				DataIntegrityCallback: dataIntegrityCallback2.Call,
+			},
+		}))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
 	"github.com/buildbarn/bb-storage/pkg/clock"
 	"github.com/buildbarn/bb-storage/pkg/digest"
+	bb_grpc "github.com/buildbarn/bb-storage/pkg/grpc"
 	"github.com/buildbarn/bb-storage/pkg/util"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -44,9 +45,37 @@ var (
 			Help:      "Amount of time spent per operation on blob access objects, in seconds.",
 			Buckets:   util.DecimalExponentialBuckets(-3, 6, 2),
 		},
-		[]string{"name", "operation", "grpc_code"})
+		[]string{"name", "operation", "grpc_code", "tool_name"})
+
+	// blobAccessOperationsToolNames contains the names of the tools
+	// that are reported through the "tool_name" label. Tool names
+	// are provided by clients through REv2 RequestMetadata, meaning
+	// they can't be used as label values directly without causing
+	// an unbounded number of metrics to be created.
+	blobAccessOperationsToolNames = map[string]struct{}{
+		"bazel": {},
+		"buck":  {},
+		"goma":  {},
+		"pants": {},
+		"recc":  {},
+	}
 )
 
+// getToolNameLabel returns the value of the "tool_name" label for an
+// operation, based on the REv2 RequestMetadata attached to its
+// Context. Tools that are not known are reported as "other".
+func getToolNameLabel(ctx context.Context) string {
+	requestMetadata, ok := bb_grpc.RequestMetadataFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	toolName := requestMetadata.GetToolDetails().GetToolName()
+	if _, ok := blobAccessOperationsToolNames[toolName]; !ok {
+		return "other"
+	}
+	return toolName
+}
+
 type metricsBlobAccess struct {
 	blobAccess BlobAccess
 	clock      clock.Clock
@@ -60,7 +89,10 @@ type metricsBlobAccess struct {
 }
 
 // NewMetricsBlobAccess creates an adapter for BlobAccess that adds
-// basic instrumentation in the form of Prometheus metrics.
+// basic instrumentation in the form of Prometheus metrics. Operation
+// durations are labeled by the name of the tool that issued the
+// request, as stored in the REv2 RequestMetadata attached to the
+// Context.
 func NewMetricsBlobAccess(blobAccess BlobAccess, clock clock.Clock, name string) BlobAccess {
 	blobAccessOperationsPrometheusMetrics.Do(func() {
 		prometheus.MustRegister(blobAccessOperationsBlobSizeBytes)
@@ -81,8 +113,8 @@ func NewMetricsBlobAccess(blobAccess BlobAccess, clock clock.Clock, name string)
 	}
 }
 
-func (ba *metricsBlobAccess) updateDurationSeconds(vec prometheus.ObserverVec, code codes.Code, timeStart time.Time) {
-	vec.WithLabelValues(code.String()).Observe(ba.clock.Now().Sub(timeStart).Seconds())
+func (ba *metricsBlobAccess) updateDurationSeconds(vec prometheus.ObserverVec, code codes.Code, toolName string, timeStart time.Time) {
+	vec.WithLabelValues(code.String(), toolName).Observe(ba.clock.Now().Sub(timeStart).Seconds())
 }
 
 func (ba *metricsBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
@@ -90,6 +122,7 @@ func (ba *metricsBlobAccess) Get(ctx context.Context, digest digest.Digest) buff
 		ba.blobAccess.Get(ctx, digest),
 		&metricsErrorHandler{
 			blobAccess: ba,
+			toolName:   getToolNameLabel(ctx),
 			timeStart:  ba.clock.Now(),
 			errorCode:  codes.OK,
 		})
@@ -111,7 +144,7 @@ func (ba *metricsBlobAccess) Put(ctx context.Context, digest digest.Digest, b bu
 
 	timeStart := ba.clock.Now()
 	err = ba.blobAccess.Put(ctx, digest, b)
-	ba.updateDurationSeconds(ba.putDurationSeconds, status.Code(err), timeStart)
+	ba.updateDurationSeconds(ba.putDurationSeconds, status.Code(err), getToolNameLabel(ctx), timeStart)
 	return err
 }
 
@@ -126,12 +159,13 @@ func (ba *metricsBlobAccess) FindMissing(ctx context.Context, digests digest.Set
 	ba.findMissingBatchSize.Observe(float64(digests.Length()))
 	timeStart := ba.clock.Now()
 	digests, err := ba.blobAccess.FindMissing(ctx, digests)
-	ba.updateDurationSeconds(ba.findMissingDurationSeconds, status.Code(err), timeStart)
+	ba.updateDurationSeconds(ba.findMissingDurationSeconds, status.Code(err), getToolNameLabel(ctx), timeStart)
 	return digests, err
 }
 
 type metricsErrorHandler struct {
 	blobAccess *metricsBlobAccess
+	toolName   string
 	timeStart  time.Time
 	errorCode  codes.Code
 }
@@ -142,5 +176,5 @@ func (eh *metricsErrorHandler) OnError(err error) (buffer.Buffer, error) {
 }
 
 func (eh *metricsErrorHandler) Done() {
-	eh.blobAccess.updateDurationSeconds(eh.blobAccess.getDurationSeconds, eh.errorCode, eh.timeStart)
+	eh.blobAccess.updateDurationSeconds(eh.blobAccess.getDurationSeconds, eh.errorCode, eh.toolName, eh.timeStart)
 }
@@ -0,0 +1,108 @@
+package blobstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	bb_grpc "github.com/buildbarn/bb-storage/pkg/grpc"
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// getMetricsBlobAccessToolNames returns the number of operations
+// observed by MetricsBlobAccess for a given backend name, keyed by
+// operation and "tool_name" label.
+func getMetricsBlobAccessToolNames(t *testing.T, name string) map[string]map[string]uint64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	counts := map[string]map[string]uint64{}
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "buildbarn_blobstore_blob_access_operations_duration_seconds" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["name"] != name {
+				continue
+			}
+			operation := labels["operation"]
+			if counts[operation] == nil {
+				counts[operation] = map[string]uint64{}
+			}
+			counts[operation][labels["tool_name"]] += metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return counts
+}
+
+func TestMetricsBlobAccessToolName(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	clock := mock.NewMockClock(ctrl)
+	clock.EXPECT().Now().Return(time.Unix(1000, 0)).AnyTimes()
+	blobAccess := blobstore.NewMetricsBlobAccess(baseBlobAccess, clock, "metrics_tool_name_test")
+
+	blobDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+	newContextWithToolName := func(toolName string) context.Context {
+		return bb_grpc.NewContextWithRequestMetadata(ctx, &remoteexecution.RequestMetadata{
+			ToolDetails: &remoteexecution.ToolDetails{
+				ToolName: toolName,
+			},
+		})
+	}
+
+	// Tools that are part of the allow list should be reported
+	// under their own name. Other tools should be reported as
+	// "other", so that clients can't cause an unbounded number of
+	// metrics to be created. Requests without any RequestMetadata
+	// should have an empty label value.
+	baseBlobAccess.EXPECT().FindMissing(gomock.Any(), blobDigest.ToSingletonSet()).
+		Return(digest.EmptySet, nil).
+		Times(7)
+	for _, toolName := range []string{"bazel", "buck", "goma", "pants", "recc", "my-custom-tool"} {
+		_, err := blobAccess.FindMissing(newContextWithToolName(toolName), blobDigest.ToSingletonSet())
+		require.NoError(t, err)
+	}
+	_, err := blobAccess.FindMissing(ctx, blobDigest.ToSingletonSet())
+	require.NoError(t, err)
+
+	// The label should also be applied to Get() calls, where the
+	// duration is only reported after the buffer is consumed.
+	baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).Return(
+		buffer.NewBufferFromError(status.Error(codes.Unavailable, "Server offline")))
+	_, err = blobAccess.Get(newContextWithToolName("bazel"), blobDigest).ToByteSlice(100)
+	require.Equal(t, status.Error(codes.Unavailable, "Server offline"), err)
+
+	require.Equal(
+		t,
+		map[string]map[string]uint64{
+			"FindMissing": {
+				"":      1,
+				"bazel": 1,
+				"buck":  1,
+				"goma":  1,
+				"other": 1,
+				"pants": 1,
+				"recc":  1,
+			},
+			"Get": {
+				"bazel": 1,
+			},
+		},
+		getMetricsBlobAccessToolNames(t, "metrics_tool_name_test"))
+}
@@ -0,0 +1,165 @@
+package mirrored
+
+import (
+	"context"
+	"sync"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	mirroringBlobAccessPrometheusMetrics sync.Once
+
+	mirroringBlobAccessPutFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "buildbarn",
+			Subsystem: "blobstore",
+			Name:      "mirroring_blob_access_put_failures_total",
+			Help:      "Number of Put() calls that failed against one of the backends, while succeeding against the other",
+		},
+		[]string{"backend"})
+	mirroringBlobAccessPutFailuresBackendA = mirroringBlobAccessPutFailures.WithLabelValues("A")
+	mirroringBlobAccessPutFailuresBackendB = mirroringBlobAccessPutFailures.WithLabelValues("B")
+)
+
+type mirroringBlobAccess struct {
+	backendA         blobstore.BlobAccess
+	backendB         blobstore.BlobAccess
+	maximumSizeBytes int
+}
+
+// NewMirroringBlobAccess creates a BlobAccess that writes blobs to two
+// storage backends and reads them from either one of them.
+//
+// Unlike the implementation returned by NewMirroredBlobAccess(), this
+// implementation does not attempt to repair inconsistencies between
+// both backends. Instead, it favours availability. Put() only fails in
+// case both backends fail to store the blob. Get() falls back to
+// backend B in case backend A fails to return the blob. FindMissing()
+// only reports blobs as missing if they are absent from both backends,
+// and continues to function if one of the backends is unavailable.
+//
+// Blobs up to maximumSizeBytes in size are copied into memory when
+// written, so that both backends can ingest them independently. Failures
+// of Put() against a single backend are not logged, as these may occur
+// at a high rate while a backend is unavailable. They are exposed
+// through a Prometheus metric instead.
+func NewMirroringBlobAccess(backendA blobstore.BlobAccess, backendB blobstore.BlobAccess, maximumSizeBytes int) blobstore.BlobAccess {
+	mirroringBlobAccessPrometheusMetrics.Do(func() {
+		prometheus.MustRegister(mirroringBlobAccessPutFailures)
+	})
+
+	return &mirroringBlobAccess{
+		backendA:         backendA,
+		backendB:         backendB,
+		maximumSizeBytes: maximumSizeBytes,
+	}
+}
+
+func (ba *mirroringBlobAccess) Get(ctx context.Context, digest digest.Digest) buffer.Buffer {
+	return buffer.WithErrorHandler(
+		ba.backendA.Get(ctx, digest),
+		&mirroringErrorHandler{
+			backendB: ba.backendB,
+			context:  ctx,
+			digest:   digest,
+		})
+}
+
+func (ba *mirroringBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	// Store object in both storage backends. Use CloneCopy() for
+	// small objects, so that a backend that is slow to ingest the
+	// object does not hold back the other. Larger objects are
+	// cloned using CloneStream(), as CloneCopy() would fail for
+	// them. This requires both backends to consume the object in
+	// lockstep, though a backend that fails will discard its copy,
+	// allowing the other backend to continue.
+	var b1, b2 buffer.Buffer
+	if sizeBytes, err := b.GetSizeBytes(); err == nil && sizeBytes <= int64(ba.maximumSizeBytes) {
+		b1, b2 = b.CloneCopy(ba.maximumSizeBytes)
+	} else {
+		b1, b2 = b.CloneStream()
+	}
+	errAChan := make(chan error, 1)
+	go func() {
+		errAChan <- ba.backendA.Put(ctx, digest, b1)
+	}()
+	errB := ba.backendB.Put(ctx, digest, b2)
+	errA := <-errAChan
+
+	// Only fail if neither backend was able to store the object.
+	if errA != nil && errB != nil {
+		return util.StatusWrap(errA, "Backend A")
+	}
+	if errA != nil {
+		mirroringBlobAccessPutFailuresBackendA.Inc()
+	}
+	if errB != nil {
+		mirroringBlobAccessPutFailuresBackendB.Inc()
+	}
+	return nil
+}
+
+func (ba *mirroringBlobAccess) FindMissing(ctx context.Context, digests digest.Set) (digest.Set, error) {
+	// Call FindMissing() on both backends.
+	resultsAChan := make(chan findMissingResults, 1)
+	go func() {
+		resultsAChan <- callFindMissing(ctx, ba.backendA, digests)
+	}()
+	resultsB := callFindMissing(ctx, ba.backendB, digests)
+	resultsA := <-resultsAChan
+
+	// If one of the backends is unavailable, rely on the results
+	// of the other backend.
+	if resultsA.err != nil {
+		if resultsB.err != nil {
+			return digest.EmptySet, util.StatusWrap(resultsA.err, "Backend A")
+		}
+		return resultsB.missing, nil
+	}
+	if resultsB.err != nil {
+		return resultsA.missing, nil
+	}
+
+	// Blobs are only missing if they are absent from both backends.
+	_, missingFromBoth, _ := digest.GetDifferenceAndIntersection(resultsA.missing, resultsB.missing)
+	return missingFromBoth, nil
+}
+
+type mirroringErrorHandler struct {
+	backendB blobstore.BlobAccess
+	context  context.Context
+	digest   digest.Digest
+	errA     error
+}
+
+func (eh *mirroringErrorHandler) OnError(err error) (buffer.Buffer, error) {
+	if eh.backendB != nil {
+		// Backend A failed to return the object. Consult
+		// backend B, as it may still have a copy of it.
+		b := eh.backendB.Get(eh.context, eh.digest)
+		eh.backendB = nil
+		eh.errA = err
+		return b, nil
+	}
+
+	// Both backends failed. Only return NotFound in case both
+	// backends agree that the object is absent. Otherwise, return
+	// the error that is most likely to be of interest.
+	if status.Code(eh.errA) != codes.NotFound {
+		return nil, util.StatusWrap(eh.errA, "Backend A")
+	}
+	if status.Code(err) != codes.NotFound {
+		return nil, util.StatusWrap(err, "Backend B")
+	}
+	return nil, err
+}
+
+func (eh *mirroringErrorHandler) Done() {}
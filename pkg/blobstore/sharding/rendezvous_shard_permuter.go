@@ -0,0 +1,76 @@
+package sharding
+
+import (
+	"math"
+	"sort"
+)
+
+type rendezvousShardPermuter struct {
+	weights []float64
+}
+
+// NewRendezvousShardPermuter is a shard selection algorithm that is
+// based on weighted Rendezvous Hashing (also known as Highest Random
+// Weight hashing). For every hash, a score is computed for every
+// backend. Backends are returned in order of decreasing score.
+//
+// Unlike the permuter returned by NewWeightedShardPermuter(), adding a
+// backend to the end of the list only causes keys to be moved to the
+// new backend. The fraction of keys that is moved is proportional to
+// the weight of the new backend. Keys are never moved between
+// existing backends.
+//
+// https://en.wikipedia.org/wiki/Rendezvous_hashing
+func NewRendezvousShardPermuter(weights []uint32) ShardPermuter {
+	s := &rendezvousShardPermuter{
+		weights: make([]float64, 0, len(weights)),
+	}
+	for _, weight := range weights {
+		s.weights = append(s.weights, float64(weight))
+	}
+	return s
+}
+
+// mixRendezvousHash is the finalizer of the SplitMix64 pseudo-random
+// number generator. It is used to turn a hash and backend index into a
+// value that is uniformly distributed.
+func mixRendezvousHash(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+type rendezvousScore struct {
+	index int
+	score float64
+}
+
+func (s *rendezvousShardPermuter) GetShard(hash uint64, selector ShardSelector) {
+	// Compute a score for every backend. The score is derived from
+	// a value in the range (0, 1), so that the probability of a
+	// backend obtaining the highest score is proportional to its
+	// weight.
+	scores := make([]rendezvousScore, 0, len(s.weights))
+	for index, weight := range s.weights {
+		x := mixRendezvousHash(hash ^ mixRendezvousHash(uint64(index)+1))
+		u := (float64(x>>11) + 0.5) / (1 << 53)
+		scores = append(scores, rendezvousScore{
+			index: index,
+			score: weight / -math.Log(u),
+		})
+	}
+	sort.Slice(scores, func(i int, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	// Return backends in order of decreasing score. Start over in
+	// case all backends have been returned, as the selector may
+	// continue to request additional backends.
+	for {
+		for _, score := range scores {
+			if !selector(score.index) {
+				return
+			}
+		}
+	}
+}
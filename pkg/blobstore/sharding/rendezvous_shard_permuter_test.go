@@ -0,0 +1,77 @@
+package sharding_test
+
+import (
+	"testing"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/sharding"
+	"github.com/stretchr/testify/require"
+)
+
+func getFirstShard(s sharding.ShardPermuter, hash uint64) int {
+	var shard int
+	s.GetShard(hash, func(i int) bool {
+		shard = i
+		return false
+	})
+	return shard
+}
+
+func TestRendezvousShardPermuterDeterminism(t *testing.T) {
+	weights := []uint32{1, 4, 2, 5, 3}
+	s1 := sharding.NewRendezvousShardPermuter(weights)
+	s2 := sharding.NewRendezvousShardPermuter(weights)
+
+	// Instances created with the same weights should yield the
+	// same permutation for a given hash.
+	for hash := uint64(0); hash < 1000; hash++ {
+		var permutation1, permutation2 []int
+		s1.GetShard(hash, func(i int) bool {
+			permutation1 = append(permutation1, i)
+			return len(permutation1) < len(weights)
+		})
+		s2.GetShard(hash, func(i int) bool {
+			permutation2 = append(permutation2, i)
+			return len(permutation2) < len(weights)
+		})
+		require.Equal(t, permutation1, permutation2)
+
+		// Every backend should be returned exactly once.
+		require.ElementsMatch(t, []int{0, 1, 2, 3, 4}, permutation1)
+	}
+}
+
+func TestRendezvousShardPermuterDistribution(t *testing.T) {
+	// Distribution across five backends with a total weight of 15.
+	weights := []uint32{1, 4, 2, 5, 3}
+	s := sharding.NewRendezvousShardPermuter(weights)
+
+	occurrences := map[int]uint32{}
+	for hash := uint64(0); hash < 1000000; hash++ {
+		occurrences[getFirstShard(s, hash*0x9e3779b97f4a7c15)]++
+	}
+
+	// Requests should be fanned out with a small error margin.
+	for shard, weight := range weights {
+		require.InEpsilon(t, weight*1000000/15, occurrences[shard], 0.01)
+	}
+}
+
+func TestRendezvousShardPermuterAddBackend(t *testing.T) {
+	// Add a sixth backend with weight 5, causing the total weight
+	// to increase from 15 to 20.
+	sOld := sharding.NewRendezvousShardPermuter([]uint32{1, 4, 2, 5, 3})
+	sNew := sharding.NewRendezvousShardPermuter([]uint32{1, 4, 2, 5, 3, 5})
+
+	moved := 0
+	for hash := uint64(0); hash < 1000000; hash++ {
+		h := hash * 0x9e3779b97f4a7c15
+		if oldShard, newShard := getFirstShard(sOld, h), getFirstShard(sNew, h); oldShard != newShard {
+			// Keys may only move to the new backend.
+			require.Equal(t, 5, newShard)
+			moved++
+		}
+	}
+
+	// Only a quarter of the keys should be moved.
+	require.InEpsilon(t, 250000, moved, 0.01)
+}
@@ -0,0 +1,182 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+)
+
+type singleFlightBlobAccess struct {
+	BlobAccess
+	maximumSizeBytes int
+
+	lock  sync.Mutex
+	calls map[string]*singleFlightCall
+}
+
+// NewSingleFlightBlobAccess creates a decorator for a Content
+// Addressable Storage (CAS) backed BlobAccess that deduplicates
+// concurrent requests for the same object. When many clients request
+// the same object simultaneously, only a single call to Get() is
+// performed against the backend. The resulting data is shared with all
+// of the callers.
+//
+// Data is fetched from the backend in the background, using a context
+// that carries the values of the first caller's context, but not its
+// cancelation. This ensures that cancelation of one of the callers does
+// not cause the shared fetch to fail for the others. The shared fetch
+// is only canceled once all of its callers have gone away, so that
+// calls against a backend that hangs don't linger indefinitely. Errors
+// returned by the backend are propagated to all of the callers.
+//
+// As data of shared fetches is held in memory, only objects up to a
+// given size are deduplicated. Requests for larger objects are
+// forwarded to the backend directly.
+func NewSingleFlightBlobAccess(base BlobAccess, maximumSizeBytes int) BlobAccess {
+	return &singleFlightBlobAccess{
+		BlobAccess:       base,
+		maximumSizeBytes: maximumSizeBytes,
+		calls:            map[string]*singleFlightCall{},
+	}
+}
+
+func (ba *singleFlightBlobAccess) Get(ctx context.Context, blobDigest digest.Digest) buffer.Buffer {
+	if blobDigest.GetSizeBytes() > int64(ba.maximumSizeBytes) {
+		return ba.BlobAccess.Get(ctx, blobDigest)
+	}
+
+	// Join an existing fetch for the same object, or start a new
+	// one if none exists.
+	key := blobDigest.GetKey(digest.KeyWithInstance)
+	ba.lock.Lock()
+	call, ok := ba.calls[key]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.Background())
+		call = &singleFlightCall{
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		ba.calls[key] = call
+		go ba.fetch(
+			singleFlightContext{
+				Context: fetchCtx,
+				values:  ctx,
+			},
+			key,
+			blobDigest,
+			call)
+	}
+	call.waiters++
+	ba.lock.Unlock()
+
+	// The data has already been validated by the buffer returned by
+	// the backend. It is still wrapped in a CAS buffer, as opposed
+	// to returning it through NewValidatedBufferFromByteSlice(), so
+	// that errors of the shared fetch are reported lazily, in a way
+	// that lets decorators such as ReadFallbackBlobAccess apply
+	// their ErrorHandlers to them.
+	return buffer.NewCASBufferFromReader(
+		blobDigest,
+		&singleFlightReader{
+			ctx:  ctx,
+			ba:   ba,
+			key:  key,
+			call: call,
+		},
+		buffer.BackendProvided(buffer.Irreparable(blobDigest)))
+}
+
+func (ba *singleFlightBlobAccess) fetch(ctx context.Context, key string, blobDigest digest.Digest, call *singleFlightCall) {
+	call.data, call.err = ba.BlobAccess.Get(ctx, blobDigest).ToByteSlice(ba.maximumSizeBytes)
+
+	// Remove the call before waking up callers, so that subsequent
+	// requests cause the object to be fetched once more. The call
+	// may already have been removed if all callers went away.
+	ba.lock.Lock()
+	if ba.calls[key] == call {
+		delete(ba.calls, key)
+	}
+	ba.lock.Unlock()
+	call.cancel()
+	close(call.done)
+}
+
+// leave is called when a caller no longer waits for a shared fetch to
+// complete. The fetch is canceled if no callers remain.
+func (ba *singleFlightBlobAccess) leave(key string, call *singleFlightCall) {
+	ba.lock.Lock()
+	defer ba.lock.Unlock()
+	call.waiters--
+	if call.waiters == 0 && ba.calls[key] == call {
+		delete(ba.calls, key)
+		call.cancel()
+	}
+}
+
+// singleFlightContext is the context that is used to perform shared
+// fetches. It provides the values of the context of the caller that
+// initiated the fetch (e.g., for tracing and authentication), while
+// cancelation is controlled by singleFlightBlobAccess.
+type singleFlightContext struct {
+	context.Context
+	values context.Context
+}
+
+func (ctx singleFlightContext) Value(key interface{}) interface{} {
+	return ctx.values.Value(key)
+}
+
+// singleFlightCall keeps track of the state of a single fetch against
+// the backend that is shared by one or more callers.
+type singleFlightCall struct {
+	cancel  context.CancelFunc
+	waiters int
+	done    chan struct{}
+	data    []byte
+	err     error
+}
+
+// singleFlightReader is the io.ReadCloser that is returned to callers
+// of singleFlightBlobAccess.Get(). It blocks until the shared fetch
+// has completed, or until the caller's context is canceled.
+type singleFlightReader struct {
+	ctx  context.Context
+	ba   *singleFlightBlobAccess
+	key  string
+	call *singleFlightCall
+	left bool
+	r    *bytes.Reader
+}
+
+func (r *singleFlightReader) leave() {
+	if !r.left {
+		r.left = true
+		r.ba.leave(r.key, r.call)
+	}
+}
+
+func (r *singleFlightReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		select {
+		case <-r.call.done:
+			r.leave()
+		case <-r.ctx.Done():
+			r.leave()
+			return 0, util.StatusFromContext(r.ctx)
+		}
+		if r.call.err != nil {
+			return 0, r.call.err
+		}
+		r.r = bytes.NewReader(r.call.data)
+	}
+	return r.r.Read(p)
+}
+
+func (r *singleFlightReader) Close() error {
+	r.leave()
+	return nil
+}
@@ -0,0 +1,152 @@
+package blobstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/buildbarn/bb-storage/internal/mock"
+	"github.com/buildbarn/bb-storage/pkg/blobstore"
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/buildbarn/bb-storage/pkg/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type singleFlightTestKey struct{}
+
+func TestSingleFlightBlobAccessGet(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	baseBlobAccess := mock.NewMockBlobAccess(ctrl)
+	blobAccess := blobstore.NewSingleFlightBlobAccess(baseBlobAccess, 100)
+	blobDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)
+
+	t.Run("Deduplication", func(t *testing.T) {
+		// Many simultaneous requests for the same object should
+		// only cause a single request against the backend.
+		release := make(chan struct{})
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest) buffer.Buffer {
+				<-release
+				return buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))
+			})
+
+		buffers := make([]buffer.Buffer, 10)
+		for i := range buffers {
+			buffers[i] = blobAccess.Get(ctx, blobDigest)
+		}
+		close(release)
+
+		var wg sync.WaitGroup
+		for _, b := range buffers {
+			wg.Add(1)
+			go func(b buffer.Buffer) {
+				defer wg.Done()
+				data, err := b.ToByteSlice(100)
+				require.NoError(t, err)
+				require.Equal(t, []byte("Hello"), data)
+			}(b)
+		}
+		wg.Wait()
+	})
+
+	t.Run("BackendFailure", func(t *testing.T) {
+		// Errors should be propagated to all callers.
+		release := make(chan struct{})
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest) buffer.Buffer {
+				<-release
+				return buffer.NewBufferFromError(status.Error(codes.Internal, "Server on fire"))
+			})
+
+		b1 := blobAccess.Get(ctx, blobDigest)
+		b2 := blobAccess.Get(ctx, blobDigest)
+		close(release)
+
+		_, err := b1.ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Internal, "Server on fire"), err)
+		_, err = b2.ToByteSlice(100)
+		require.Equal(t, status.Error(codes.Internal, "Server on fire"), err)
+	})
+
+	t.Run("Cancelation", func(t *testing.T) {
+		// Cancelation of one of the callers should not cause
+		// the shared fetch to be interrupted.
+		release := make(chan struct{})
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest) buffer.Buffer {
+				<-release
+				require.NoError(t, ctx.Err())
+				return buffer.NewValidatedBufferFromByteSlice([]byte("Hello"))
+			})
+
+		ctx1, cancel1 := context.WithCancel(ctx)
+		b1 := blobAccess.Get(ctx1, blobDigest)
+		b2 := blobAccess.Get(ctx, blobDigest)
+
+		cancel1()
+		_, err := b1.ToByteSlice(100)
+		require.Equal(t, codes.Canceled, status.Code(err))
+
+		close(release)
+		data, err := b2.ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+
+	t.Run("HungBackend", func(t *testing.T) {
+		// The shared fetch should be performed using the values
+		// of the context of the first caller. Once all callers
+		// have gone away, the shared fetch should be canceled.
+		// Subsequent requests should not join it.
+		backendCanceled := make(chan struct{})
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).DoAndReturn(
+			func(ctx context.Context, digest digest.Digest) buffer.Buffer {
+				require.Equal(t, "value", ctx.Value(singleFlightTestKey{}))
+				<-ctx.Done()
+				close(backendCanceled)
+				return buffer.NewBufferFromError(util.StatusFromContext(ctx))
+			})
+
+		ctx1, cancel1 := context.WithCancel(context.WithValue(ctx, singleFlightTestKey{}, "value"))
+		b1 := blobAccess.Get(ctx1, blobDigest)
+		ctx2, cancel2 := context.WithCancel(ctx)
+		b2 := blobAccess.Get(ctx2, blobDigest)
+
+		cancel1()
+		_, err := b1.ToByteSlice(100)
+		require.Equal(t, codes.Canceled, status.Code(err))
+		select {
+		case <-backendCanceled:
+			t.Fatal("Shared fetch was canceled while a caller was still waiting")
+		default:
+		}
+
+		cancel2()
+		_, err = b2.ToByteSlice(100)
+		require.Equal(t, codes.Canceled, status.Code(err))
+		<-backendCanceled
+
+		baseBlobAccess.EXPECT().Get(gomock.Any(), blobDigest).Return(
+			buffer.NewValidatedBufferFromByteSlice([]byte("Hello")))
+		data, err := blobAccess.Get(ctx, blobDigest).ToByteSlice(100)
+		require.NoError(t, err)
+		require.Equal(t, []byte("Hello"), data)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		// Requests for large objects should be forwarded
+		// directly.
+		largeDigest := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 1000)
+		baseBlobAccess.EXPECT().Get(ctx, largeDigest).Return(
+			buffer.NewBufferFromError(status.Error(codes.NotFound, "Object not found")))
+
+		_, err := blobAccess.Get(ctx, largeDigest).ToByteSlice(1000)
+		require.Equal(t, status.Error(codes.NotFound, "Object not found"), err)
+	})
+}
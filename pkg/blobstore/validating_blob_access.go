@@ -0,0 +1,32 @@
+package blobstore
+
+import (
+	"context"
+
+	"github.com/buildbarn/bb-storage/pkg/blobstore/buffer"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+)
+
+type validatingBlobAccess struct {
+	BlobAccess
+}
+
+// NewValidatingBlobAccess creates a decorator for a Content Addressable
+// Storage (CAS) backed BlobAccess that independently recomputes the
+// checksum of every blob that is written, regardless of how the buffer
+// provided to Put() was constructed. Buffers that were created with
+// NewValidatedBufferFromByteSlice() or similar are normally trusted to
+// be correct. This decorator may be used in deployments where such
+// trust is not desired.
+//
+// Blobs whose contents do not match the digest are rejected with
+// codes.InvalidArgument, causing them not to be stored.
+func NewValidatingBlobAccess(base BlobAccess) BlobAccess {
+	return &validatingBlobAccess{
+		BlobAccess: base,
+	}
+}
+
+func (ba *validatingBlobAccess) Put(ctx context.Context, digest digest.Digest, b buffer.Buffer) error {
+	return ba.BlobAccess.Put(ctx, digest, buffer.NewCASBufferFromReader(digest, b.ToReader(), buffer.UserProvided))
+}
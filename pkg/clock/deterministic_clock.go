@@ -0,0 +1,162 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeterministicClock is an implementation of Clock whose time only
+// changes when Advance() is called. Timers and contexts with timeouts
+// created through it fire as soon as the clock is advanced past their
+// deadline. Unlike mock.MockClock, it does not require every call to
+// be scripted, making it suitable for testing code that calls Now()
+// many times.
+//
+// It is safe to access DeterministicClock concurrently.
+type DeterministicClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*deterministicTimer
+}
+
+// NewDeterministicClock creates a DeterministicClock whose current time
+// is equal to the provided value.
+func NewDeterministicClock(start time.Time) *DeterministicClock {
+	return &DeterministicClock{
+		now: start,
+	}
+}
+
+// Now returns the current time of the DeterministicClock.
+func (c *DeterministicClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Advance the current time of the DeterministicClock by a given
+// duration. Timers whose deadline is reached are fired in order of
+// their deadline.
+func (c *DeterministicClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var expired []*deterministicTimer
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			remaining = append(remaining, t)
+		} else {
+			expired = append(expired, t)
+		}
+	}
+	for i := len(remaining); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = remaining
+
+	sort.SliceStable(expired, func(i int, j int) bool {
+		return expired[i].deadline.Before(expired[j].deadline)
+	})
+	for _, t := range expired {
+		t.channel <- t.deadline
+	}
+}
+
+// NewContextWithTimeout creates a Context object that is automatically
+// canceled once the DeterministicClock is advanced past the timeout.
+func (c *DeterministicClock) NewContextWithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctxWithCancel, cancel := context.WithCancel(parent)
+	ctx := &deterministicContext{
+		Context:  ctxWithCancel,
+		deadline: c.Now().Add(timeout),
+	}
+	if parentDeadline, ok := parent.Deadline(); ok && parentDeadline.Before(ctx.deadline) {
+		ctx.deadline = parentDeadline
+	}
+
+	timer, channel := c.NewTimer(timeout)
+	go func() {
+		select {
+		case <-channel:
+			ctx.lock.Lock()
+			if ctxWithCancel.Err() == nil {
+				ctx.timedOut = true
+			}
+			ctx.lock.Unlock()
+			cancel()
+		case <-ctxWithCancel.Done():
+			timer.Stop()
+		}
+	}()
+	return ctx, cancel
+}
+
+// NewTimer creates a timer that fires once the DeterministicClock is
+// advanced by at least the provided duration.
+func (c *DeterministicClock) NewTimer(d time.Duration) (Timer, <-chan time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := &deterministicTimer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		channel:  make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.channel <- t.deadline
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	return t, t.channel
+}
+
+type deterministicTimer struct {
+	clock    *DeterministicClock
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func (t *deterministicTimer) Stop() bool {
+	c := t.clock
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// deterministicContext is a Context object that is returned by
+// DeterministicClock.NewContextWithTimeout(). It reports the deadline
+// as computed by the DeterministicClock, and returns
+// context.DeadlineExceeded once the timeout has been reached.
+type deterministicContext struct {
+	context.Context
+	deadline time.Time
+
+	lock     sync.Mutex
+	timedOut bool
+}
+
+func (ctx *deterministicContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+func (ctx *deterministicContext) Err() error {
+	ctx.lock.Lock()
+	timedOut := ctx.timedOut
+	ctx.lock.Unlock()
+	if timedOut {
+		return context.DeadlineExceeded
+	}
+	return ctx.Context.Err()
+}
@@ -0,0 +1,135 @@
+package clock_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/buildbarn/bb-storage/pkg/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicClockNow(t *testing.T) {
+	c := clock.NewDeterministicClock(time.Unix(1000, 0))
+
+	t.Run("Initial", func(t *testing.T) {
+		// The clock should not move on its own.
+		require.Equal(t, time.Unix(1000, 0), c.Now())
+		require.Equal(t, time.Unix(1000, 0), c.Now())
+	})
+
+	t.Run("Advance", func(t *testing.T) {
+		c.Advance(time.Second)
+		require.Equal(t, time.Unix(1001, 0), c.Now())
+		c.Advance(time.Minute)
+		require.Equal(t, time.Unix(1061, 0), c.Now())
+	})
+
+	t.Run("AdvanceConcurrently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				c.Advance(time.Second)
+				c.Now()
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, time.Unix(1161, 0), c.Now())
+	})
+}
+
+func TestDeterministicClockNewTimer(t *testing.T) {
+	c := clock.NewDeterministicClock(time.Unix(1000, 0))
+
+	t.Run("Fire", func(t *testing.T) {
+		_, channel := c.NewTimer(10 * time.Second)
+
+		// The timer should not fire before its deadline.
+		c.Advance(9 * time.Second)
+		select {
+		case <-channel:
+			t.Fatal("Timer fired before its deadline")
+		default:
+		}
+
+		// Once the deadline is reached, the timer should fire
+		// with the time at which it was scheduled.
+		c.Advance(time.Second)
+		require.Equal(t, time.Unix(1010, 0), <-channel)
+	})
+
+	t.Run("Multiple", func(t *testing.T) {
+		// All timers whose deadline is reached by a single call
+		// to Advance() should fire.
+		_, channel1 := c.NewTimer(3 * time.Second)
+		_, channel2 := c.NewTimer(time.Second)
+		_, channel3 := c.NewTimer(time.Hour)
+		c.Advance(time.Minute)
+		require.Equal(t, time.Unix(1013, 0), <-channel1)
+		require.Equal(t, time.Unix(1011, 0), <-channel2)
+		select {
+		case <-channel3:
+			t.Fatal("Timer fired before its deadline")
+		default:
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		// Stopped timers should never fire.
+		timer, channel := c.NewTimer(time.Second)
+		require.True(t, timer.Stop())
+		c.Advance(time.Minute)
+		select {
+		case <-channel:
+			t.Fatal("Stopped timer fired")
+		default:
+		}
+
+		// Stopping a timer that already fired should fail.
+		timer, channel = c.NewTimer(time.Second)
+		c.Advance(time.Second)
+		require.False(t, timer.Stop())
+		<-channel
+	})
+
+	t.Run("Immediate", func(t *testing.T) {
+		// Timers with a non-positive duration should fire
+		// immediately.
+		_, channel := c.NewTimer(0)
+		require.Equal(t, c.Now(), <-channel)
+	})
+}
+
+func TestDeterministicClockNewContextWithTimeout(t *testing.T) {
+	c := clock.NewDeterministicClock(time.Unix(1000, 0))
+
+	t.Run("Timeout", func(t *testing.T) {
+		ctx, cancel := c.NewContextWithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		require.Equal(t, time.Unix(1060, 0), deadline)
+		require.NoError(t, ctx.Err())
+
+		// Advancing the clock past the deadline should cause
+		// the context to be canceled.
+		c.Advance(time.Minute)
+		<-ctx.Done()
+		require.Equal(t, context.DeadlineExceeded, ctx.Err())
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := c.NewContextWithTimeout(context.Background(), time.Minute)
+		cancel()
+		<-ctx.Done()
+		require.Equal(t, context.Canceled, ctx.Err())
+
+		// Advancing the clock afterwards should not change
+		// the reason of cancelation.
+		c.Advance(time.Minute)
+		require.Equal(t, context.Canceled, ctx.Err())
+	})
+}
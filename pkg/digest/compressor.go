@@ -0,0 +1,41 @@
+package digest
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Compressor of the data transferred through the ByteStream service.
+// Clients may use the "compressed-blobs" resource naming scheme to
+// transfer blobs in compressed form.
+type Compressor int
+
+const (
+	// CompressorIdentity indicates that data is transferred
+	// uncompressed. It corresponds to the "blobs" resource naming
+	// scheme.
+	CompressorIdentity Compressor = iota
+	// CompressorZstd indicates that data is transferred in
+	// Zstandard compressed form.
+	CompressorZstd
+)
+
+var compressorNames = map[Compressor]string{
+	CompressorIdentity: "identity",
+	CompressorZstd:     "zstd",
+}
+
+// newCompressorFromName converts the compressor identifier that is
+// part of the "compressed-blobs" resource naming scheme to a
+// Compressor. As the identity compressor has its own resource naming
+// scheme, it is not accepted.
+func newCompressorFromName(name string) (Compressor, error) {
+	if name == compressorNames[CompressorZstd] {
+		return CompressorZstd, nil
+	}
+	return CompressorIdentity, status.Errorf(codes.InvalidArgument, "Unsupported compressor %#v", name)
+}
+
+func (c Compressor) String() string {
+	return compressorNames[c]
+}
@@ -98,25 +98,39 @@ func MustNewDigest(instanceName string, hash string, sizeBytes int64) Digest {
 }
 
 // NewDigestFromByteStreamReadPath creates a Digest from a string having
-// the following format: ${instanceName}/blobs/${hash}/${size}. This
-// notation is used to read files through the ByteStream service.
-func NewDigestFromByteStreamReadPath(path string) (Digest, error) {
+// one of the following formats:
+//
+// - ${instanceName}/blobs/${hash}/${size}
+// - ${instanceName}/compressed-blobs/${compressor}/${hash}/${size}
+//
+// This notation is used to read files through the ByteStream service.
+// The compressor that is returned indicates in which form the client
+// expects the data to be transferred.
+func NewDigestFromByteStreamReadPath(path string) (Digest, Compressor, error) {
 	fields := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
 	if len(fields) < 3 {
-		return BadDigest, status.Error(codes.InvalidArgument, "Invalid resource naming scheme")
+		return BadDigest, CompressorIdentity, status.Error(codes.InvalidArgument, "Invalid resource naming scheme")
 	}
 	split := len(fields) - 3
+	if split > 0 && fields[split-1] == "compressed-blobs" {
+		split--
+	}
 	return newDigestFromByteStreamPathCommon(fields[:split], fields[split:])
 }
 
 // NewDigestFromByteStreamWritePath creates a Digest from a string
-// having the following format:
-// ${instanceName}/uploads/${uuid}/blobs/${hash}/${size}/${path}. This
-// notation is used to write files through the ByteStream service.
-func NewDigestFromByteStreamWritePath(path string) (Digest, error) {
+// having one of the following formats:
+//
+// - ${instanceName}/uploads/${uuid}/blobs/${hash}/${size}/${path}
+// - ${instanceName}/uploads/${uuid}/compressed-blobs/${compressor}/${hash}/${size}/${path}
+//
+// This notation is used to write files through the ByteStream service.
+// The compressor that is returned indicates in which form the client
+// transfers the data.
+func NewDigestFromByteStreamWritePath(path string) (Digest, Compressor, error) {
 	fields := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
 	if len(fields) < 5 {
-		return BadDigest, status.Errorf(codes.InvalidArgument, "Invalid resource naming scheme")
+		return BadDigest, CompressorIdentity, status.Errorf(codes.InvalidArgument, "Invalid resource naming scheme")
 	}
 	// Determine the end of the instance name. Because both the
 	// leading instance name and the trailing path have a variable
@@ -126,25 +140,60 @@ func NewDigestFromByteStreamWritePath(path string) (Digest, error) {
 	for fields[split] != "uploads" {
 		split++
 		if split > len(fields)-5 {
-			return BadDigest, status.Errorf(codes.InvalidArgument, "Invalid resource naming scheme")
+			return BadDigest, CompressorIdentity, status.Errorf(codes.InvalidArgument, "Invalid resource naming scheme")
 		}
 	}
 	return newDigestFromByteStreamPathCommon(fields[:split], fields[split+2:])
 }
 
-func newDigestFromByteStreamPathCommon(header []string, trailer []string) (Digest, error) {
-	if trailer[0] != "blobs" {
-		return BadDigest, status.Error(codes.InvalidArgument, "Invalid resource naming scheme")
+func newDigestFromByteStreamPathCommon(header []string, trailer []string) (Digest, Compressor, error) {
+	compressor := CompressorIdentity
+	switch trailer[0] {
+	case "blobs":
+	case "compressed-blobs":
+		if len(trailer) < 4 {
+			return BadDigest, CompressorIdentity, status.Error(codes.InvalidArgument, "Invalid resource naming scheme")
+		}
+		var err error
+		compressor, err = newCompressorFromName(trailer[1])
+		if err != nil {
+			return BadDigest, CompressorIdentity, err
+		}
+		trailer = trailer[1:]
+	default:
+		return BadDigest, CompressorIdentity, status.Error(codes.InvalidArgument, "Invalid resource naming scheme")
 	}
-	sizeBytes, err := strconv.ParseInt(trailer[2], 10, 64)
+	sizeBytes, err := parseByteStreamSizeBytes(trailer[2])
 	if err != nil {
-		return BadDigest, status.Errorf(codes.InvalidArgument, "Invalid blob size %#v", trailer[2])
+		return BadDigest, CompressorIdentity, err
 	}
 	instanceName, err := NewInstanceNameFromComponents(header)
 	if err != nil {
-		return BadDigest, util.StatusWrapf(err, "Invalid instance name %#v", strings.Join(header, "/"))
+		return BadDigest, CompressorIdentity, util.StatusWrapf(err, "Invalid instance name %#v", strings.Join(header, "/"))
+	}
+	d, err := instanceName.NewDigest(trailer[1], sizeBytes)
+	if err != nil {
+		return BadDigest, CompressorIdentity, err
 	}
-	return instanceName.NewDigest(trailer[1], sizeBytes)
+	return d, compressor, nil
+}
+
+// parseByteStreamSizeBytes parses the size of a blob that is part of a
+// ByteStream resource name. Only the canonical decimal notation is
+// accepted, as produced by GetByteStream{Read,Write}Path(). This
+// prevents multiple resource names from referring to the same blob.
+func parseByteStreamSizeBytes(s string) (int64, error) {
+	if strings.HasPrefix(s, "-") {
+		return 0, status.Errorf(codes.InvalidArgument, "Blob size %#v is negative", s)
+	}
+	if strings.HasPrefix(s, "+") || (len(s) > 1 && s[0] == '0') {
+		return 0, status.Errorf(codes.InvalidArgument, "Blob size %#v is not in canonical form", s)
+	}
+	sizeBytes, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "Invalid blob size %#v", s)
+	}
+	return sizeBytes, nil
 }
 
 // GetByteStreamReadPath converts the Digest to a string having
@@ -193,13 +242,44 @@ func (d Digest) GetInstanceName() InstanceName {
 	}
 }
 
+// WithInstance returns a copy of the digest that has the same hash and
+// size, but a different instance name. As the hash and size have
+// already been validated, only the new instance name is validated.
+func (d Digest) WithInstance(instance string) (Digest, error) {
+	if _, err := NewInstanceName(instance); err != nil {
+		return BadDigest, err
+	}
+	_, _, sizeBytesEnd := d.unpack()
+	return Digest{
+		value: d.value[:sizeBytesEnd+1] + instance,
+	}, nil
+}
+
 // GetHashBytes returns the hash of the object as a slice of bytes.
 func (d Digest) GetHashBytes() []byte {
-	hash, err := hex.DecodeString(d.GetHashString())
-	if err != nil {
+	return d.AppendHashBytes(make([]byte, 0, d.GetHashSizeBytes()))
+}
+
+// AppendHashBytes appends the hash of the object to a slice of bytes.
+// Unlike GetHashBytes(), this function permits callers to reuse
+// storage when decoding the hashes of many digests in a loop.
+func (d Digest) AppendHashBytes(b []byte) []byte {
+	hash := d.GetHashString()
+	for i := 0; i < len(hash); i += 2 {
+		b = append(b, decodeHexDigit(hash[i])<<4|decodeHexDigit(hash[i+1]))
+	}
+	return b
+}
+
+func decodeHexDigit(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
 		panic("Failed to decode digest hash, even though its contents have already been validated")
 	}
-	return hash
 }
 
 // GetHashString returns the hash of the object as a string.
@@ -208,6 +288,14 @@ func (d Digest) GetHashString() string {
 	return d.value[:hashEnd]
 }
 
+// GetHashSizeBytes returns the size of the hash of the object, in
+// bytes. This corresponds to the output size of the hashing algorithm
+// that was used to compute the digest.
+func (d Digest) GetHashSizeBytes() int {
+	hashEnd, _, _ := d.unpack()
+	return hashEnd / 2
+}
+
 // GetSizeBytes returns the size of the object, in bytes.
 func (d Digest) GetSizeBytes() int64 {
 	_, sizeBytes, _ := d.unpack()
@@ -259,18 +347,39 @@ func (d Digest) GetKey(format KeyFormat) string {
 // through getxattr() that can be used to store a cached copy of the
 // object's hash.
 func (d Digest) GetHashXAttrName() string {
+	switch d.GetDigestFunction() {
+	case remoteexecution.DigestFunction_MD5:
+		return "user.buildbarn.hash.md5"
+	case remoteexecution.DigestFunction_SHA1:
+		return "user.buildbarn.hash.sha1"
+	case remoteexecution.DigestFunction_SHA256:
+		return "user.buildbarn.hash.sha256"
+	case remoteexecution.DigestFunction_SHA384:
+		return "user.buildbarn.hash.sha384"
+	case remoteexecution.DigestFunction_SHA512:
+		return "user.buildbarn.hash.sha512"
+	default:
+		panic("Digest hash is of unknown type")
+	}
+}
+
+// GetDigestFunction returns the digest function that was used to
+// compute the hash of the object, using the enumeration values that are
+// part of the Remote Execution protocol. The digest function is
+// derived from the length of the hash.
+func (d Digest) GetDigestFunction() remoteexecution.DigestFunction_Value {
 	hashEnd, _, _ := d.unpack()
 	switch hashEnd {
 	case md5.Size * 2:
-		return "user.buildbarn.hash.md5"
+		return remoteexecution.DigestFunction_MD5
 	case sha1.Size * 2:
-		return "user.buildbarn.hash.sha1"
+		return remoteexecution.DigestFunction_SHA1
 	case sha256.Size * 2:
-		return "user.buildbarn.hash.sha256"
+		return remoteexecution.DigestFunction_SHA256
 	case sha512.Size384 * 2:
-		return "user.buildbarn.hash.sha384"
+		return remoteexecution.DigestFunction_SHA384
 	case sha512.Size * 2:
-		return "user.buildbarn.hash.sha512"
+		return remoteexecution.DigestFunction_SHA512
 	default:
 		panic("Digest hash is of unknown type")
 	}
@@ -280,6 +389,22 @@ func (d Digest) String() string {
 	return d.GetKey(KeyWithInstance)
 }
 
+// Equal returns whether two digests refer to the same object, using the
+// same hash, size and instance name.
+func (d Digest) Equal(other Digest) bool {
+	return d.value == other.value
+}
+
+// Compare two digests, returning -1, 0 or 1 if the digest is
+// respectively less than, equal to or greater than the other digest.
+// Digests are ordered by their string representation, which is also
+// the order in which they are stored in a Set. Digests using different
+// digest functions are thus ordered by hash first, not by digest
+// function.
+func (d Digest) Compare(other Digest) int {
+	return strings.Compare(d.value, other.value)
+}
+
 // ToSingletonSet creates a Set that contains a single element that
 // corresponds to the Digest.
 func (d Digest) ToSingletonSet() Set {
@@ -288,26 +413,35 @@ func (d Digest) ToSingletonSet() Set {
 	}
 }
 
+// NewHasher creates a standard hash.Hash object that may be used to
+// compute a checksum of data, using a given digest function.
+func NewHasher(digestFunction remoteexecution.DigestFunction_Value) (hash.Hash, error) {
+	switch digestFunction {
+	case remoteexecution.DigestFunction_MD5:
+		return md5.New(), nil
+	case remoteexecution.DigestFunction_SHA1:
+		return sha1.New(), nil
+	case remoteexecution.DigestFunction_SHA256:
+		return sha256.New(), nil
+	case remoteexecution.DigestFunction_SHA384:
+		return sha512.New384(), nil
+	case remoteexecution.DigestFunction_SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported digest function: %s", digestFunction)
+	}
+}
+
 // NewHasher creates a standard hash.Hash object that may be used to
 // compute a checksum of data. The hash.Hash object uses the same
 // algorithm as the one that was used to create the digest, making it
 // possible to validate data against a digest.
 func (d Digest) NewHasher() hash.Hash {
-	hashEnd, _, _ := d.unpack()
-	switch hashEnd {
-	case md5.Size * 2:
-		return md5.New()
-	case sha1.Size * 2:
-		return sha1.New()
-	case sha256.Size * 2:
-		return sha256.New()
-	case sha512.Size384 * 2:
-		return sha512.New384()
-	case sha512.Size * 2:
-		return sha512.New()
-	default:
-		panic("Digest hash is of unknown type")
+	h, err := NewHasher(d.GetDigestFunction())
+	if err != nil {
+		panic(err)
 	}
+	return h
 }
 
 // NewGenerator creates a writer that may be used to compute digests of
@@ -1,6 +1,9 @@
 package digest_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"testing"
 
 	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
@@ -14,102 +17,166 @@ import (
 
 func TestNewDigestFromByteStreamReadPath(t *testing.T) {
 	t.Run("Empty", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamReadPath("")
+		_, _, err := digest.NewDigestFromByteStreamReadPath("")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"))
 	})
 
 	t.Run("BlabsInsteadOfBlobs", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamReadPath("blabs/8b1a9953c4611296a827abf8c47804d7/123")
+		_, _, err := digest.NewDigestFromByteStreamReadPath("blabs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"))
 	})
 
 	t.Run("NonIntegerSize", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/five")
+		_, _, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/five")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid blob size \"five\""))
 	})
 
+	t.Run("NonCanonicalSize", func(t *testing.T) {
+		for size, expectedErr := range map[string]error{
+			"-5":                   status.Error(codes.InvalidArgument, "Blob size \"-5\" is negative"),
+			"+5":                   status.Error(codes.InvalidArgument, "Blob size \"+5\" is not in canonical form"),
+			"007":                  status.Error(codes.InvalidArgument, "Blob size \"007\" is not in canonical form"),
+			"00":                   status.Error(codes.InvalidArgument, "Blob size \"00\" is not in canonical form"),
+			"99999999999999999999": status.Error(codes.InvalidArgument, "Invalid blob size \"99999999999999999999\""),
+		} {
+			_, _, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/" + size)
+			require.Equal(t, expectedErr, err, size)
+		}
+	})
+
+	t.Run("CanonicalSize", func(t *testing.T) {
+		for size, expectedSizeBytes := range map[string]int64{
+			"0": 0,
+			"5": 5,
+		} {
+			d, _, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/" + size)
+			require.NoError(t, err)
+			require.Equal(t, digest.MustNewDigest("", "8b1a9953c4611296a827abf8c47804d7", expectedSizeBytes), d)
+		}
+	})
+
 	t.Run("InvalidInstanceName", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamReadPath("x/operations/y/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		_, _, err := digest.NewDigestFromByteStreamReadPath("x/operations/y/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid instance name \"x/operations/y\": Instance name contains reserved keyword \"operations\""))
 	})
 
 	t.Run("NoInstanceName", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamReadPath("blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("InstanceNameOneComponent", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamReadPath("hello/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamReadPath("hello/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("InstanceNameTwoComponents", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamReadPath("hello/world/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamReadPath("hello/world/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("RedundantSlashes", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamReadPath("//hello//world//blobs//8b1a9953c4611296a827abf8c47804d7//123//")
+		d, compressor, err := digest.NewDigestFromByteStreamReadPath("//hello//world//blobs//8b1a9953c4611296a827abf8c47804d7//123//")
+		require.NoError(t, err)
+		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
+	})
+
+	t.Run("CompressedZstd", func(t *testing.T) {
+		d, compressor, err := digest.NewDigestFromByteStreamReadPath("hello/world/compressed-blobs/zstd/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorZstd, compressor)
+	})
+
+	t.Run("CompressedUnknownCompressor", func(t *testing.T) {
+		_, _, err := digest.NewDigestFromByteStreamReadPath("hello/compressed-blobs/lz4/8b1a9953c4611296a827abf8c47804d7/123")
+		require.Equal(t, err, status.Error(codes.InvalidArgument, "Unsupported compressor \"lz4\""))
+	})
+
+	t.Run("CompressedIdentity", func(t *testing.T) {
+		// Uncompressed transfers must use the "blobs" resource
+		// naming scheme instead.
+		_, _, err := digest.NewDigestFromByteStreamReadPath("compressed-blobs/identity/8b1a9953c4611296a827abf8c47804d7/123")
+		require.Equal(t, err, status.Error(codes.InvalidArgument, "Unsupported compressor \"identity\""))
 	})
 }
 
 func TestNewDigestFromByteStreamWritePath(t *testing.T) {
 	t.Run("Empty", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamWritePath("")
+		_, _, err := digest.NewDigestFromByteStreamWritePath("")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"))
 	})
 
 	t.Run("DownloadsInsteadOfUploads", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamWritePath("downloads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		_, _, err := digest.NewDigestFromByteStreamWritePath("downloads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid resource naming scheme"))
 	})
 
 	t.Run("NonIntegerSize", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamWritePath("uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/five")
+		_, _, err := digest.NewDigestFromByteStreamWritePath("uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/five")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid blob size \"five\""))
 	})
 
 	t.Run("InvalidInstanceName", func(t *testing.T) {
-		_, err := digest.NewDigestFromByteStreamWritePath("x/operations/y/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		_, _, err := digest.NewDigestFromByteStreamWritePath("x/operations/y/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.Equal(t, err, status.Error(codes.InvalidArgument, "Invalid instance name \"x/operations/y\": Instance name contains reserved keyword \"operations\""))
 	})
 
 	t.Run("NoInstanceName", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamWritePath("uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("InstanceNameOneComponent", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamWritePath("hello/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("hello/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("InstanceNameTwoComponents", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamWritePath("hello/world/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("hello/world/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("RedundantSlashes", func(t *testing.T) {
-		d, err := digest.NewDigestFromByteStreamWritePath("//hello//world//uploads//da2f1135-326b-4956-b920-1646cdd6cb53//blobs//8b1a9953c4611296a827abf8c47804d7//123//")
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("//hello//world//uploads//da2f1135-326b-4956-b920-1646cdd6cb53//blobs//8b1a9953c4611296a827abf8c47804d7//123//")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
 	})
 
 	t.Run("TrailingPath", func(t *testing.T) {
 		// Upload paths may contain a trailing filename that the
 		// implementation can use to attach a name to the
 		// object. This implementation ignores that information.
-		d, err := digest.NewDigestFromByteStreamWritePath("hello/world/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123/this/file/is/called/foo.txt")
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("hello/world/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/blobs/8b1a9953c4611296a827abf8c47804d7/123/this/file/is/called/foo.txt")
+		require.NoError(t, err)
+		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorIdentity, compressor)
+	})
+
+	t.Run("CompressedZstd", func(t *testing.T) {
+		d, compressor, err := digest.NewDigestFromByteStreamWritePath("hello/world/uploads/da2f1135-326b-4956-b920-1646cdd6cb53/compressed-blobs/zstd/8b1a9953c4611296a827abf8c47804d7/123/foo.txt")
 		require.NoError(t, err)
 		require.Equal(t, digest.MustNewDigest("hello/world", "8b1a9953c4611296a827abf8c47804d7", 123), d)
+		require.Equal(t, digest.CompressorZstd, compressor)
+	})
+
+	t.Run("CompressedUnknownCompressor", func(t *testing.T) {
+		_, _, err := digest.NewDigestFromByteStreamWritePath("uploads/da2f1135-326b-4956-b920-1646cdd6cb53/compressed-blobs/lz4/8b1a9953c4611296a827abf8c47804d7/123")
+		require.Equal(t, err, status.Error(codes.InvalidArgument, "Unsupported compressor \"lz4\""))
 	})
 }
 
@@ -202,6 +269,28 @@ func TestDigestGetInstanceName(t *testing.T) {
 			123).GetInstanceName())
 }
 
+func TestDigestWithInstance(t *testing.T) {
+	d := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 123)
+
+	t.Run("Success", func(t *testing.T) {
+		for _, instanceName := range []string{"", "world", "hello/world"} {
+			rewritten, err := d.WithInstance(instanceName)
+			require.NoError(t, err)
+			require.Equal(t, digest.MustNewDigest(instanceName, "8b1a9953c4611296a827abf8c47804d7", 123), rewritten)
+			require.Equal(t, d.GetKey(digest.KeyWithoutInstance), rewritten.GetKey(digest.KeyWithoutInstance))
+			require.Equal(t, instanceName, rewritten.GetInstanceName().String())
+		}
+	})
+
+	t.Run("InvalidInstanceName", func(t *testing.T) {
+		_, err := d.WithInstance("hello/blobs")
+		require.Equal(t, status.Error(codes.InvalidArgument, "Instance name contains reserved keyword \"blobs\""), err)
+
+		_, err = d.WithInstance("/hello")
+		require.Equal(t, status.Error(codes.InvalidArgument, "Instance name contains redundant slashes"), err)
+	})
+}
+
 func TestDigestGetHashBytes(t *testing.T) {
 	require.Equal(
 		t,
@@ -217,6 +306,43 @@ func TestDigestGetHashBytes(t *testing.T) {
 			123).GetHashBytes())
 }
 
+func TestDigestAppendHashBytes(t *testing.T) {
+	// Hashes should be appended to existing contents, so that
+	// storage may be reused.
+	require.Equal(
+		t,
+		[]byte{
+			0x01, 0x02,
+			0x8b, 0x1a, 0x99, 0x53, 0xc4, 0x61, 0x12, 0x96,
+			0xa8, 0x27, 0xab, 0xf8, 0xc4, 0x78, 0x04, 0xd7,
+		},
+		digest.MustNewDigest(
+			"hello",
+			"8b1a9953c4611296a827abf8c47804d7",
+			123).AppendHashBytes([]byte{0x01, 0x02}))
+}
+
+func BenchmarkDigestAppendHashBytes(b *testing.B) {
+	// Construct a set of digests similar to what is provided to
+	// FindMissing(), and decode all of their hashes.
+	setBuilder := digest.NewSetBuilder()
+	for i := 0; i < 1000; i++ {
+		hasher := sha256.New()
+		fmt.Fprintf(hasher, "%d", i)
+		setBuilder.Add(digest.MustNewDigest("hello", hex.EncodeToString(hasher.Sum(nil)), int64(i)))
+	}
+	digests := setBuilder.Build().Items()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var hash []byte
+	for n := 0; n < b.N; n++ {
+		for _, d := range digests {
+			hash = d.AppendHashBytes(hash[:0])
+		}
+	}
+}
+
 func TestDigestGetHashString(t *testing.T) {
 	require.Equal(
 		t,
@@ -227,6 +353,23 @@ func TestDigestGetHashString(t *testing.T) {
 			123).GetHashString())
 }
 
+func TestDigestGetHashSizeBytes(t *testing.T) {
+	for _, e := range []struct {
+		hash          string
+		hashSizeBytes int
+	}{
+		{"8b1a9953c4611296a827abf8c47804d7", 16},
+		{"f7ff9e8b7bb2e09b70935a5d785e0cc5d9d0abf0", 20},
+		{"185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 32},
+		{"3519fe5ad2c596efe3e276a6f351b8fc0b03db861782490d45f7598ebd0ab5fd5520ed102f38c4a5ec834e98668035fc", 48},
+		{"3615f80c9d293ed7402687f94b22d58e529b8cc7916f8fac7fddf7fbd5af4cf777d3d795a7a00a16bf7e7f3fb9561ee9baae480da9fe7a18769e71886b03f315", 64},
+	} {
+		require.Equal(t,
+			e.hashSizeBytes,
+			digest.MustNewDigest("hello", e.hash, 123).GetHashSizeBytes())
+	}
+}
+
 func TestDigestGetSizeBytes(t *testing.T) {
 	require.Equal(
 		t,
@@ -249,6 +392,34 @@ func TestDigestGetKey(t *testing.T) {
 		d.GetKey(digest.KeyWithInstance))
 }
 
+func TestDigestInstanceNameRoundTrip(t *testing.T) {
+	// The instance name is stored at the end of the key format.
+	// Instance names containing characters that are also used as
+	// separators in keys and paths can thus be recovered without
+	// any form of escaping.
+	for _, instanceName := range []string{
+		"tenant-1",
+		"tenant-123-8b1a9953c4611296a827abf8c47804d7",
+		"hello/world",
+		"hello/tenant-1/world",
+		"100%",
+		"%2F",
+	} {
+		t.Run(instanceName, func(t *testing.T) {
+			d := digest.MustNewDigest(instanceName, "8b1a9953c4611296a827abf8c47804d7", 123)
+			require.Equal(t, instanceName, d.GetInstanceName().String())
+			require.Equal(t, "8b1a9953c4611296a827abf8c47804d7", d.GetHashString())
+			require.Equal(t, int64(123), d.GetSizeBytes())
+			require.Equal(t, "8b1a9953c4611296a827abf8c47804d7-123", d.GetKey(digest.KeyWithoutInstance))
+			require.Equal(t, "8b1a9953c4611296a827abf8c47804d7-123-"+instanceName, d.GetKey(digest.KeyWithInstance))
+
+			parsed, _, err := digest.NewDigestFromByteStreamReadPath(d.GetByteStreamReadPath())
+			require.NoError(t, err)
+			require.Equal(t, d, parsed)
+		})
+	}
+}
+
 func TestDigestGetHashXAttrName(t *testing.T) {
 	for _, e := range []struct{ hash, xattrName string }{
 		{"8b1a9953c4611296a827abf8c47804d7", "user.buildbarn.hash.md5"},
@@ -263,6 +434,48 @@ func TestDigestGetHashXAttrName(t *testing.T) {
 	}
 }
 
+func TestDigestGetDigestFunction(t *testing.T) {
+	for _, e := range []struct {
+		hash           string
+		digestFunction remoteexecution.DigestFunction_Value
+	}{
+		{"8b1a9953c4611296a827abf8c47804d7", remoteexecution.DigestFunction_MD5},
+		{"f7ff9e8b7bb2e09b70935a5d785e0cc5d9d0abf0", remoteexecution.DigestFunction_SHA1},
+		{"185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", remoteexecution.DigestFunction_SHA256},
+		{"3519fe5ad2c596efe3e276a6f351b8fc0b03db861782490d45f7598ebd0ab5fd5520ed102f38c4a5ec834e98668035fc", remoteexecution.DigestFunction_SHA384},
+		{"3615f80c9d293ed7402687f94b22d58e529b8cc7916f8fac7fddf7fbd5af4cf777d3d795a7a00a16bf7e7f3fb9561ee9baae480da9fe7a18769e71886b03f315", remoteexecution.DigestFunction_SHA512},
+	} {
+		require.Equal(t,
+			e.digestFunction,
+			digest.MustNewDigest("hello", e.hash, 123).GetDigestFunction())
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	for _, e := range []struct {
+		hash           string
+		digestFunction remoteexecution.DigestFunction_Value
+	}{
+		{"8b1a9953c4611296a827abf8c47804d7", remoteexecution.DigestFunction_MD5},
+		{"f7ff9e8b7bb2e09b70935a5d785e0cc5d9d0abf0", remoteexecution.DigestFunction_SHA1},
+		{"185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", remoteexecution.DigestFunction_SHA256},
+		{"3519fe5ad2c596efe3e276a6f351b8fc0b03db861782490d45f7598ebd0ab5fd5520ed102f38c4a5ec834e98668035fc", remoteexecution.DigestFunction_SHA384},
+		{"3615f80c9d293ed7402687f94b22d58e529b8cc7916f8fac7fddf7fbd5af4cf777d3d795a7a00a16bf7e7f3fb9561ee9baae480da9fe7a18769e71886b03f315", remoteexecution.DigestFunction_SHA512},
+	} {
+		t.Run(e.digestFunction.String(), func(t *testing.T) {
+			hasher, err := digest.NewHasher(e.digestFunction)
+			require.NoError(t, err)
+			hasher.Write([]byte("Hello"))
+			require.Equal(t, e.hash, hex.EncodeToString(hasher.Sum(nil)))
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := digest.NewHasher(remoteexecution.DigestFunction_VSO)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Unsupported digest function: VSO"), err)
+	})
+}
+
 func TestDigestString(t *testing.T) {
 	require.Equal(
 		t,
@@ -273,6 +486,36 @@ func TestDigestString(t *testing.T) {
 			123).String())
 }
 
+func TestDigestEqualAndCompare(t *testing.T) {
+	// Digests in ascending order. Digests using different digest
+	// functions are ordered by hash. Sizes are compared
+	// lexicographically, as they are part of the string
+	// representation.
+	digests := []digest.Digest{
+		digest.MustNewDigest("a", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5),
+		digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 123),
+		digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5),
+		digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5),
+		digest.MustNewDigest("a", "a54d88e06612d820bc3be72877c74f257b561b19", 14),
+	}
+	for i, dI := range digests {
+		for j, dJ := range digests {
+			require.Equal(t, i == j, dI.Equal(dJ))
+			switch {
+			case i < j:
+				require.Equal(t, -1, dI.Compare(dJ))
+			case i == j:
+				require.Equal(t, 0, dI.Compare(dJ))
+			default:
+				require.Equal(t, 1, dI.Compare(dJ))
+			}
+		}
+	}
+
+	// Digests that are constructed separately should be equal.
+	require.True(t, digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5).Equal(digests[2]))
+}
+
 func TestDigestToSingletonSet(t *testing.T) {
 	d := digest.MustNewDigest("hello", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 123)
 	require.Equal(
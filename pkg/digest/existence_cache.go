@@ -26,6 +26,16 @@ type ExistenceCache struct {
 }
 
 // NewExistenceCache creates a new ExistenceCache that is empty.
+//
+// The key format determines whether entries are shared between
+// instance names. With KeyWithInstance, the presence of an object is
+// tracked for every instance name separately. With KeyWithoutInstance,
+// an object that was observed to be present for one instance name is
+// also assumed to be present for all others. This is only safe if the
+// backend stores objects without taking the instance name into
+// account, as is done by the Content Addressable Storage when using
+// local storage. Callers should therefore use the key format of the
+// backend whose results are being cached.
 func NewExistenceCache(clock clock.Clock, keyFormat KeyFormat, cacheSize int, cacheDuration time.Duration, evictionSet eviction.Set) *ExistenceCache {
 	return &ExistenceCache{
 		clock:         clock,
@@ -7,6 +7,7 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 
@@ -20,15 +21,48 @@ var (
 	// parsing of URLs, such as the ones provided to the ByteStream
 	// service, ambiguous.
 	reservedInstanceNameKeywords = map[string]bool{
-		"blobs":         true,
-		"uploads":       true,
-		"actions":       true,
-		"actionResults": true,
-		"operations":    true,
-		"capabilities":  true,
+		"blobs":            true,
+		"uploads":          true,
+		"actions":          true,
+		"actionResults":    true,
+		"operations":       true,
+		"capabilities":     true,
+		"compressed-blobs": true,
+	}
+
+	// Hashes of the empty blob, for each of the supported digest
+	// functions.
+	emptyBlobHashes = map[remoteexecution.DigestFunction_Value]string{
+		remoteexecution.DigestFunction_MD5:    "d41d8cd98f00b204e9800998ecf8427e",
+		remoteexecution.DigestFunction_SHA1:   "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		remoteexecution.DigestFunction_SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		remoteexecution.DigestFunction_SHA384: "38b060a751ac96384cd9327eb1b1e36a21fdb71114be07434c0cc7bf63f6e1da274edebfe76f65fbd51ad2f14898b95b",
+		remoteexecution.DigestFunction_SHA512: "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e",
 	}
 )
 
+// DefaultMaximumInstanceNameLengthBytes is the maximum length of
+// instance names that is used if SetMaximumInstanceNameLengthBytes()
+// is not called.
+const DefaultMaximumInstanceNameLengthBytes = 1024
+
+var maximumInstanceNameLengthBytes uint32 = DefaultMaximumInstanceNameLengthBytes
+
+// SetMaximumInstanceNameLengthBytes adjusts the maximum length of
+// instance names that may be created. Instance names are embedded in
+// keys used by storage backends and in log messages. Placing an upper
+// bound on their length prevents clients from bloating these.
+func SetMaximumInstanceNameLengthBytes(length uint32) {
+	atomic.StoreUint32(&maximumInstanceNameLengthBytes, length)
+}
+
+func validateInstanceNameLength(length int) error {
+	if maximumLength := atomic.LoadUint32(&maximumInstanceNameLengthBytes); length > int(maximumLength) {
+		return status.Errorf(codes.InvalidArgument, "Instance name is %d bytes in size, while a maximum of %d bytes is permitted", length, maximumLength)
+	}
+	return nil
+}
+
 // InstanceName is a simple container around REv2 instance name strings.
 // Because instance names are embedded in URLs, the REv2 protocol places
 // some restrictions on which instance names are valid. This type can
@@ -57,6 +91,9 @@ func validateInstanceNameComponents(components []string) error {
 // NewInstanceName creates a new InstanceName object that can be used to
 // parse digests.
 func NewInstanceName(value string) (InstanceName, error) {
+	if err := validateInstanceNameLength(len(value)); err != nil {
+		return InstanceName{}, err
+	}
 	if strings.HasPrefix(value, "/") || strings.HasSuffix(value, "/") || strings.Contains(value, "//") {
 		return InstanceName{}, status.Error(codes.InvalidArgument, "Instance name contains redundant slashes")
 	}
@@ -73,11 +110,15 @@ func NewInstanceName(value string) (InstanceName, error) {
 // that it takes a series of pathname components instead of a single
 // string.
 func NewInstanceNameFromComponents(components []string) (InstanceName, error) {
+	value := strings.Join(components, "/")
+	if err := validateInstanceNameLength(len(value)); err != nil {
+		return InstanceName{}, err
+	}
 	if err := validateInstanceNameComponents(components); err != nil {
 		return InstanceName{}, err
 	}
 	return InstanceName{
-		value: strings.Join(components, "/"),
+		value: value,
 	}, nil
 }
 
@@ -125,6 +166,32 @@ func (in InstanceName) NewDigestFromProto(digest *remoteexecution.Digest) (Diges
 	return in.NewDigest(digest.Hash, digest.SizeBytes)
 }
 
+// NewEmptyDigest constructs a Digest object that corresponds to the
+// empty blob, computed using a given digest function. This avoids the
+// need for computing a checksum over no input.
+func (in InstanceName) NewEmptyDigest(digestFunction remoteexecution.DigestFunction_Value) (Digest, error) {
+	hash, ok := emptyBlobHashes[digestFunction]
+	if !ok {
+		return BadDigest, status.Errorf(codes.InvalidArgument, "Unsupported digest function: %s", digestFunction)
+	}
+	return in.newDigestUnchecked(hash, 0), nil
+}
+
+// NewGenerator creates a writer that may be used to compute digests of
+// newly created files, using a given digest function. Unlike
+// Digest.NewGenerator(), it does not require a digest of the same kind
+// to be available up front.
+func (in InstanceName) NewGenerator(digestFunction remoteexecution.DigestFunction_Value) (*Generator, error) {
+	partialHash, err := NewHasher(digestFunction)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{
+		instanceName: in,
+		partialHash:  partialHash,
+	}, nil
+}
+
 // newDigestUnchecked constructs a Digest object from an instance name,
 // hash and object size without validating its contents.
 func (in InstanceName) newDigestUnchecked(hash string, sizeBytes int64) Digest {
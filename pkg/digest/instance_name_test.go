@@ -3,6 +3,7 @@ package digest_test
 import (
 	"testing"
 
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/stretchr/testify/require"
 
@@ -35,6 +36,31 @@ func TestNewInstanceName(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, digest.EmptyInstanceName, instanceName)
 	})
+
+	t.Run("MaximumLength", func(t *testing.T) {
+		digest.SetMaximumInstanceNameLengthBytes(11)
+		defer digest.SetMaximumInstanceNameLengthBytes(digest.DefaultMaximumInstanceNameLengthBytes)
+
+		// Instance names up to the maximum length are permitted.
+		instanceName, err := digest.NewInstanceName("hello/worl")
+		require.NoError(t, err)
+		require.Equal(t, "hello/worl", instanceName.String())
+
+		instanceName, err = digest.NewInstanceName("hello/world")
+		require.NoError(t, err)
+		require.Equal(t, "hello/world", instanceName.String())
+
+		instanceName, err = digest.NewInstanceNameFromComponents([]string{"hello", "world"})
+		require.NoError(t, err)
+		require.Equal(t, "hello/world", instanceName.String())
+
+		// Longer instance names should be rejected.
+		_, err = digest.NewInstanceName("hello/world!")
+		require.Equal(t, status.Error(codes.InvalidArgument, "Instance name is 12 bytes in size, while a maximum of 11 bytes is permitted"), err)
+
+		_, err = digest.NewInstanceNameFromComponents([]string{"hello", "world!"})
+		require.Equal(t, status.Error(codes.InvalidArgument, "Instance name is 12 bytes in size, while a maximum of 11 bytes is permitted"), err)
+	})
 }
 
 func TestInstanceNameNewDigest(t *testing.T) {
@@ -49,3 +75,48 @@ func TestInstanceNameNewDigest(t *testing.T) {
 	_, err = instanceName.NewDigest("00000000000000000000000000000000", -1)
 	require.Equal(t, status.Error(codes.InvalidArgument, "Invalid digest size: -1 bytes"), err)
 }
+
+func TestInstanceNameNewEmptyDigest(t *testing.T) {
+	instanceName := digest.MustNewInstanceName("hello")
+
+	for _, digestFunction := range []remoteexecution.DigestFunction_Value{
+		remoteexecution.DigestFunction_MD5,
+		remoteexecution.DigestFunction_SHA1,
+		remoteexecution.DigestFunction_SHA256,
+		remoteexecution.DigestFunction_SHA384,
+		remoteexecution.DigestFunction_SHA512,
+	} {
+		t.Run(digestFunction.String(), func(t *testing.T) {
+			// The digest should match the one that is computed
+			// by hashing no input.
+			emptyDigest, err := instanceName.NewEmptyDigest(digestFunction)
+			require.NoError(t, err)
+			require.Equal(t, digestFunction, emptyDigest.GetDigestFunction())
+			require.Equal(t, emptyDigest, emptyDigest.NewGenerator().Sum())
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := instanceName.NewEmptyDigest(remoteexecution.DigestFunction_VSO)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Unsupported digest function: VSO"), err)
+	})
+}
+
+func TestInstanceNameNewGenerator(t *testing.T) {
+	instanceName := digest.MustNewInstanceName("hello")
+
+	t.Run("SHA256", func(t *testing.T) {
+		generator, err := instanceName.NewGenerator(remoteexecution.DigestFunction_SHA256)
+		require.NoError(t, err)
+		generator.Write([]byte("Hello"))
+		require.Equal(
+			t,
+			digest.MustNewDigest("hello", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5),
+			generator.Sum())
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := instanceName.NewGenerator(remoteexecution.DigestFunction_VSO)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Unsupported digest function: VSO"), err)
+	})
+}
@@ -0,0 +1,86 @@
+package digest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/buildbarn/bb-storage/pkg/clock"
+	"github.com/buildbarn/bb-storage/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	generatorPrometheusMetrics sync.Once
+
+	generatorHashedBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "buildbarn",
+			Subsystem: "digest",
+			Name:      "generator_hashed_bytes_total",
+			Help:      "Number of bytes hashed by digest generators.",
+		},
+		[]string{"digest_function"})
+	generatorHashingDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "buildbarn",
+			Subsystem: "digest",
+			Name:      "generator_hashing_duration_seconds",
+			Help:      "Amount of time spent hashing a single blob, in seconds.",
+			Buckets:   util.DecimalExponentialBuckets(-6, 7, 2),
+		},
+		[]string{"digest_function"})
+)
+
+// MetricsGenerator is a decorator for Generator that exposes Prometheus
+// metrics on the number of bytes hashed, and the amount of time spent
+// hashing them.
+//
+// Only time spent inside Write() is accounted for. Time spent by the
+// caller obtaining the data to be hashed (e.g., reading it from disk
+// or the network) is thus not included.
+type MetricsGenerator struct {
+	generator *Generator
+	clock     clock.Clock
+	duration  time.Duration
+
+	hashedBytes            prometheus.Counter
+	hashingDurationSeconds prometheus.Observer
+}
+
+// NewMetricsGenerator creates a MetricsGenerator that uses the same
+// algorithm as the one that was used to create the digest. It can be
+// used in place of NewGenerator() by callers that wish to keep track
+// of hashing throughput.
+func (d Digest) NewMetricsGenerator(clock clock.Clock) *MetricsGenerator {
+	generatorPrometheusMetrics.Do(func() {
+		prometheus.MustRegister(generatorHashedBytesTotal)
+		prometheus.MustRegister(generatorHashingDurationSeconds)
+	})
+
+	digestFunction := d.GetDigestFunction().String()
+	return &MetricsGenerator{
+		generator: d.NewGenerator(),
+		clock:     clock,
+
+		hashedBytes:            generatorHashedBytesTotal.WithLabelValues(digestFunction),
+		hashingDurationSeconds: generatorHashingDurationSeconds.WithLabelValues(digestFunction),
+	}
+}
+
+// Write a chunk of data from a newly created file into the state of the
+// MetricsGenerator.
+func (dg *MetricsGenerator) Write(p []byte) (int, error) {
+	timeStart := dg.clock.Now()
+	n, err := dg.generator.Write(p)
+	dg.duration += dg.clock.Now().Sub(timeStart)
+	dg.hashedBytes.Add(float64(n))
+	return n, err
+}
+
+// Sum creates a new digest based on the data written into the
+// MetricsGenerator. The total amount of time spent hashing is recorded
+// as part of this call.
+func (dg *MetricsGenerator) Sum() Digest {
+	dg.hashingDurationSeconds.Observe(dg.duration.Seconds())
+	return dg.generator.Sum()
+}
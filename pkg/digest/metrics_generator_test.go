@@ -0,0 +1,45 @@
+package digest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buildbarn/bb-storage/pkg/clock"
+	"github.com/buildbarn/bb-storage/pkg/digest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsGenerator(t *testing.T) {
+	generator := digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5).
+		NewMetricsGenerator(clock.NewDeterministicClock(time.Unix(1000, 0)))
+
+	// The number of bytes hashed should be accounted for as soon as
+	// data is written.
+	n, err := generator.Write([]byte("Hel"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(`
+# HELP buildbarn_digest_generator_hashed_bytes_total Number of bytes hashed by digest generators.
+# TYPE buildbarn_digest_generator_hashed_bytes_total counter
+buildbarn_digest_generator_hashed_bytes_total{digest_function="MD5"} 3
+`), "buildbarn_digest_generator_hashed_bytes_total"))
+
+	n, err = generator.Write([]byte("lo"))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(`
+# HELP buildbarn_digest_generator_hashed_bytes_total Number of bytes hashed by digest generators.
+# TYPE buildbarn_digest_generator_hashed_bytes_total counter
+buildbarn_digest_generator_hashed_bytes_total{digest_function="MD5"} 5
+`), "buildbarn_digest_generator_hashed_bytes_total"))
+
+	// The resulting digest should be identical to the one computed
+	// by an uninstrumented Generator.
+	require.Equal(
+		t,
+		digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5),
+		generator.Sum())
+}
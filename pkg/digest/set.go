@@ -2,6 +2,12 @@ package digest
 
 import (
 	"container/heap"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/buildbarn/bb-storage/pkg/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Set of digests. Sets are immutable and can be created using
@@ -15,6 +21,36 @@ var (
 	EmptySet = Set{}
 )
 
+// NewSetFromProto creates a Set from a list of protocol-level digest
+// objects, all of which are assumed to belong to the same instance
+// name. This is the inverse of Set.ToProto().
+func NewSetFromProto(instanceName InstanceName, digests []*remoteexecution.Digest) (Set, error) {
+	sb := NewSetBuilder()
+	for i, digest := range digests {
+		d, err := instanceName.NewDigestFromProto(digest)
+		if err != nil {
+			return EmptySet, util.StatusWrapf(err, "Invalid digest at index %d", i)
+		}
+		sb.Add(d)
+	}
+	return sb.Build(), nil
+}
+
+// ToProto converts all elements stored in the set to protocol-level
+// digest objects, so that the set can be transmitted over the network.
+// As protocol-level digest objects don't contain instance names, all
+// elements are required to belong to the instance name provided.
+func (s Set) ToProto(instanceName InstanceName) ([]*remoteexecution.Digest, error) {
+	digests := make([]*remoteexecution.Digest, 0, len(s.digests))
+	for _, d := range s.digests {
+		if in := d.GetInstanceName(); in != instanceName {
+			return nil, status.Errorf(codes.InvalidArgument, "Digest %#v does not belong to instance name %#v", d.String(), instanceName.String())
+		}
+		digests = append(digests, d.GetProto())
+	}
+	return digests, nil
+}
+
 // Items returns a sorted list of all elements stored within the set.
 func (s Set) Items() []Digest {
 	return s.digests
@@ -40,28 +76,39 @@ func (s Set) Length() int {
 	return len(s.digests)
 }
 
-// RemoveEmptyBlob returns a copy of the set that has all of the entries
-// corresponding with the empty blob removed.
-func (s Set) RemoveEmptyBlob() Set {
+// Filter returns a copy of the set that only contains the entries for
+// which a predicate returns true.
+func (s Set) Filter(predicate func(Digest) bool) Set {
 	for start, digest := range s.digests {
-		if digest.GetSizeBytes() == 0 {
-			// At least one non-empty blob was found. Copy
-			// the set up to this point and filter all
+		if !predicate(digest) {
+			// At least one element needs to be removed.
+			// Copy the set up to this point and filter all
 			// successive results.
-			nonEmptyBlobs := append([]Digest(nil), s.digests[:start]...)
+			filtered := append([]Digest(nil), s.digests[:start]...)
 			for _, digest := range s.digests[start+1:] {
-				if digest.GetSizeBytes() != 0 {
-					nonEmptyBlobs = append(nonEmptyBlobs, digest)
+				if predicate(digest) {
+					filtered = append(filtered, digest)
 				}
 			}
-			return Set{digests: nonEmptyBlobs}
+			if len(filtered) == 0 {
+				return EmptySet
+			}
+			return Set{digests: filtered}
 		}
 	}
 
-	// Return the original set, as no non-empty blobs were found.
+	// Return the original set, as no elements were removed.
 	return s
 }
 
+// RemoveEmptyBlob returns a copy of the set that has all of the entries
+// corresponding with the empty blob removed.
+func (s Set) RemoveEmptyBlob() Set {
+	return s.Filter(func(digest Digest) bool {
+		return digest.GetSizeBytes() != 0
+	})
+}
+
 // GetDifferenceAndIntersection partitions the elements stored in sets A
 // and B across three resulting sets: one containing the elements
 // present only in A, one containing the elements present in both A and
@@ -69,10 +116,10 @@ func (s Set) RemoveEmptyBlob() Set {
 func GetDifferenceAndIntersection(setA Set, setB Set) (onlyA, both Set, onlyB Set) {
 	a, b := setA.digests, setB.digests
 	for len(a) > 0 && len(b) > 0 {
-		if sA, sB := a[0].String(), b[0].String(); sA < sB {
+		if cmp := a[0].Compare(b[0]); cmp < 0 {
 			onlyA.digests = append(onlyA.digests, a[0])
 			a = a[1:]
-		} else if sA == sB {
+		} else if cmp == 0 {
 			both.digests = append(both.digests, a[0])
 			a, b = a[1:], b[1:]
 		} else {
@@ -127,7 +174,7 @@ func GetUnion(sets []Set) Set {
 		// Next iteration: copy the next lowest digest of all
 		// sets, if and only if it's distinct from the
 		// previously added digest.
-		if d := activeSets[0].digests[0]; d != outDigests[len(outDigests)-1] {
+		if d := activeSets[0].digests[0]; !d.Equal(outDigests[len(outDigests)-1]) {
 			outDigests = append(outDigests, d)
 		}
 	}
@@ -143,7 +190,7 @@ func (h *setHeap) Len() int {
 }
 
 func (h *setHeap) Less(i int, j int) bool {
-	return (*h)[i].digests[0].String() < (*h)[j].digests[0].String()
+	return (*h)[i].digests[0].Compare((*h)[j].digests[0]) < 0
 }
 
 func (h *setHeap) Swap(i int, j int) {
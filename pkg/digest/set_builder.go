@@ -57,7 +57,7 @@ func (l digestList) Len() int {
 }
 
 func (l digestList) Less(i int, j int) bool {
-	return l[i].String() < l[j].String()
+	return l[i].Compare(l[j]) < 0
 }
 
 func (l digestList) Swap(i int, j int) {
@@ -3,8 +3,12 @@ package digest_test
 import (
 	"testing"
 
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
 	"github.com/buildbarn/bb-storage/pkg/digest"
 	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestSetEmpty(t *testing.T) {
@@ -86,6 +90,47 @@ func TestSetRemoveEmptyBlob(t *testing.T) {
 			RemoveEmptyBlob())
 }
 
+func TestSetFilter(t *testing.T) {
+	set := digest.NewSetBuilder().
+		Add(digest.MustNewDigest("a", "8b1a9953c4611296a827abf8c47804d7", 5)).
+		Add(digest.MustNewDigest("a", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)).
+		Add(digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5)).
+		Add(digest.MustNewDigest("b", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)).
+		Build()
+
+	t.Run("DigestFunction", func(t *testing.T) {
+		require.Equal(
+			t,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("a", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)).
+				Add(digest.MustNewDigest("b", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)).
+				Build(),
+			set.Filter(func(d digest.Digest) bool {
+				return d.GetDigestFunction() == remoteexecution.DigestFunction_SHA256
+			}))
+	})
+
+	t.Run("InstanceName", func(t *testing.T) {
+		require.Equal(
+			t,
+			digest.NewSetBuilder().
+				Add(digest.MustNewDigest("b", "8b1a9953c4611296a827abf8c47804d7", 5)).
+				Add(digest.MustNewDigest("b", "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", 5)).
+				Build(),
+			set.Filter(func(d digest.Digest) bool {
+				return d.GetInstanceName() == digest.MustNewInstanceName("b")
+			}))
+	})
+
+	t.Run("All", func(t *testing.T) {
+		require.Equal(t, set, set.Filter(func(d digest.Digest) bool { return true }))
+	})
+
+	t.Run("None", func(t *testing.T) {
+		require.Equal(t, digest.EmptySet, set.Filter(func(d digest.Digest) bool { return false }))
+	})
+}
+
 func TestGetDifferenceAndIntersection(t *testing.T) {
 	onlyA, both, onlyB := digest.GetDifferenceAndIntersection(
 		digest.NewSetBuilder().
@@ -183,3 +228,64 @@ func TestGetUnion(t *testing.T) {
 			}).Items())
 	})
 }
+
+func TestSetProto(t *testing.T) {
+	instanceName := digest.MustNewInstanceName("hello")
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		// Sets may contain digests of different kinds.
+		set := digest.NewSetBuilder().
+			Add(digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)).
+			Add(digest.MustNewDigest("hello", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 0)).
+			Build()
+		digests, err := set.ToProto(instanceName)
+		require.NoError(t, err)
+		require.Equal(t, []*remoteexecution.Digest{
+			{
+				Hash:      "8b1a9953c4611296a827abf8c47804d7",
+				SizeBytes: 5,
+			},
+			{
+				Hash:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				SizeBytes: 0,
+			},
+		}, digests)
+
+		newSet, err := digest.NewSetFromProto(instanceName, digests)
+		require.NoError(t, err)
+		require.Equal(t, set, newSet)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		digests, err := digest.EmptySet.ToProto(instanceName)
+		require.NoError(t, err)
+		require.Empty(t, digests)
+
+		set, err := digest.NewSetFromProto(instanceName, nil)
+		require.NoError(t, err)
+		require.Equal(t, digest.EmptySet, set)
+	})
+
+	t.Run("InconsistentInstanceName", func(t *testing.T) {
+		_, err := digest.NewSetBuilder().
+			Add(digest.MustNewDigest("hello", "8b1a9953c4611296a827abf8c47804d7", 5)).
+			Add(digest.MustNewDigest("goodbye", "8b1a9953c4611296a827abf8c47804d7", 5)).
+			Build().
+			ToProto(instanceName)
+		require.Equal(t, status.Error(codes.InvalidArgument, "Digest \"8b1a9953c4611296a827abf8c47804d7-5-goodbye\" does not belong to instance name \"hello\""), err)
+	})
+
+	t.Run("InvalidDigest", func(t *testing.T) {
+		_, err := digest.NewSetFromProto(instanceName, []*remoteexecution.Digest{
+			{
+				Hash:      "8b1a9953c4611296a827abf8c47804d7",
+				SizeBytes: 5,
+			},
+			{
+				Hash:      "8b1a9953c4611296a827abf8c47804d",
+				SizeBytes: 5,
+			},
+		})
+		require.Equal(t, status.Error(codes.InvalidArgument, "Invalid digest at index 1: Unknown digest hash length: 31 characters"), err)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/buildbarn/bb-storage/pkg/digest"
 	pb "github.com/buildbarn/bb-storage/pkg/proto/configuration/global"
 	"github.com/buildbarn/bb-storage/pkg/util"
 	"github.com/golang/protobuf/ptypes"
@@ -107,6 +108,11 @@ func ApplyConfiguration(configuration *pb.Configuration) (*LifecycleState, error
 		}
 	}
 
+	// Limit the length of instance names.
+	if length := configuration.GetMaximumInstanceNameLengthBytes(); length > 0 {
+		digest.SetMaximumInstanceNameLengthBytes(length)
+	}
+
 	// Enable mutex profiling.
 	runtime.SetMutexProfileFraction(int(configuration.GetMutexProfileFraction()))
 
@@ -3,9 +3,11 @@ package grpc
 import (
 	"context"
 	"crypto/x509"
+	"regexp"
 
 	"github.com/buildbarn/bb-storage/pkg/clock"
 	configuration "github.com/buildbarn/bb-storage/pkg/proto/configuration/grpc"
+	"github.com/buildbarn/bb-storage/pkg/util"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -45,9 +47,19 @@ func NewAuthenticatorFromConfiguration(policy *configuration.AuthenticationPolic
 		if !clientCAs.AppendCertsFromPEM([]byte(policyKind.TlsClientCertificate.ClientCertificateAuthorities)) {
 			return nil, status.Error(codes.InvalidArgument, "Failed to parse client certificate authorities")
 		}
+		var allowedSubjectPattern *regexp.Regexp
+		if pattern := policyKind.TlsClientCertificate.AllowedSubjectPattern; pattern != "" {
+			var err error
+			allowedSubjectPattern, err = regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, util.StatusWrapWithCode(err, codes.InvalidArgument, "Failed to compile allowed subject pattern")
+			}
+		}
 		return NewTLSClientCertificateAuthenticator(
 			clientCAs,
-			clock.SystemClock), nil
+			clock.SystemClock,
+			policyKind.TlsClientCertificate.AllowedSubjects,
+			allowedSubjectPattern), nil
 	default:
 		return nil, status.Error(codes.InvalidArgument, "Configuration did not contain an authentication policy type")
 	}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	"github.com/golang/protobuf/proto"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// requestMetadataHeaderName is the name of the gRPC metadata header in
+// which REv2 clients store a serialized RequestMetadata message.
+const requestMetadataHeaderName = "build.bazel.remote.execution.v2.requestmetadata-bin"
+
+type requestMetadataKey struct{}
+
+// NewContextWithRequestMetadata returns a copy of a Context that has a
+// REv2 RequestMetadata message attached to it. This message can be
+// extracted by calling RequestMetadataFromContext(). This can be used
+// to correlate storage operations with build invocations, even in
+// cases where the Context does not originate from an incoming gRPC
+// call.
+func NewContextWithRequestMetadata(ctx context.Context, requestMetadata *remoteexecution.RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, requestMetadata)
+}
+
+// RequestMetadataFromContext extracts the REv2 RequestMetadata message
+// that is associated with a Context. It returns the message that was
+// attached using NewContextWithRequestMetadata(). If no such message
+// exists, it attempts to parse the message from the metadata of the
+// incoming gRPC call.
+func RequestMetadataFromContext(ctx context.Context) (*remoteexecution.RequestMetadata, bool) {
+	if requestMetadata, ok := ctx.Value(requestMetadataKey{}).(*remoteexecution.RequestMetadata); ok {
+		return requestMetadata, true
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	rmds := md.Get(requestMetadataHeaderName)
+	if len(rmds) == 0 {
+		return nil, false
+	}
+	var requestMetadata remoteexecution.RequestMetadata
+	if err := proto.Unmarshal([]byte(rmds[0]), &requestMetadata); err != nil {
+		return nil, false
+	}
+	return &requestMetadata, true
+}
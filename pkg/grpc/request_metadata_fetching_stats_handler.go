@@ -3,10 +3,6 @@ package grpc
 import (
 	"context"
 
-	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
-	"github.com/golang/protobuf/proto"
-
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 
 	"go.opencensus.io/trace"
@@ -36,22 +32,11 @@ func (rmfsh requestMetadataFetchingStatsHandler) TagRPC(ctx context.Context, rti
 		return ctx
 	}
 
-	md, ok := metadata.FromIncomingContext(ctx)
+	rmd, ok := RequestMetadataFromContext(ctx)
 	if !ok {
 		return ctx
 	}
 
-	rmds := md.Get("build.bazel.remote.execution.v2.requestmetadata-bin")
-	if len(rmds) == 0 {
-		return ctx
-	}
-
-	var rmd remoteexecution.RequestMetadata
-
-	if err := proto.Unmarshal([]byte(rmds[0]), &rmd); err != nil {
-		return ctx
-	}
-
 	span.AddAttributes(
 		trace.StringAttribute("action_id", rmd.ActionId),
 		trace.StringAttribute("tool_invocation_id", rmd.ToolInvocationId),
@@ -0,0 +1,64 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	remoteexecution "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+	bb_grpc "github.com/buildbarn/bb-storage/pkg/grpc"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestMetadataFromContext(t *testing.T) {
+	requestMetadata := &remoteexecution.RequestMetadata{
+		ToolDetails: &remoteexecution.ToolDetails{
+			ToolName:    "bazel",
+			ToolVersion: "3.4.1",
+		},
+		ActionId:         "dbd6a4c3b6e4d4f1b0a6d0b4f0cc0f2a",
+		ToolInvocationId: "3c9a3d2e-0b0a-4c8e-9b4b-0f5a6e1c7d2b",
+	}
+
+	t.Run("Absent", func(t *testing.T) {
+		_, ok := bb_grpc.RequestMetadataFromContext(context.Background())
+		require.False(t, ok)
+	})
+
+	t.Run("AttachedToContext", func(t *testing.T) {
+		// Metadata attached to a Context should be preserved
+		// when the Context is extended by decorators.
+		ctx := bb_grpc.NewContextWithRequestMetadata(context.Background(), requestMetadata)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		actualRequestMetadata, ok := bb_grpc.RequestMetadataFromContext(ctx)
+		require.True(t, ok)
+		require.True(t, proto.Equal(requestMetadata, actualRequestMetadata))
+	})
+
+	t.Run("IncomingGRPCMetadata", func(t *testing.T) {
+		// Metadata should also be extracted from the headers of
+		// incoming gRPC calls.
+		data, err := proto.Marshal(requestMetadata)
+		require.NoError(t, err)
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("build.bazel.remote.execution.v2.requestmetadata-bin", string(data)))
+
+		actualRequestMetadata, ok := bb_grpc.RequestMetadataFromContext(ctx)
+		require.True(t, ok)
+		require.True(t, proto.Equal(requestMetadata, actualRequestMetadata))
+	})
+
+	t.Run("IncomingGRPCMetadataInvalid", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs("build.bazel.remote.execution.v2.requestmetadata-bin", "\xff\xff"))
+
+		_, ok := bb_grpc.RequestMetadataFromContext(ctx)
+		require.False(t, ok)
+	})
+}
@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	"crypto/x509"
+	"regexp"
 
 	"github.com/buildbarn/bb-storage/pkg/clock"
 	"github.com/buildbarn/bb-storage/pkg/util"
@@ -14,19 +15,56 @@ import (
 )
 
 type tlsClientCertificateAuthenticator struct {
-	clientCAs *x509.CertPool
-	clock     clock.Clock
+	clientCAs             *x509.CertPool
+	clock                 clock.Clock
+	restrictSubjects      bool
+	allowedSubjects       map[string]struct{}
+	allowedSubjectPattern *regexp.Regexp
 }
 
 // NewTLSClientCertificateAuthenticator creates an Authenticator that
 // only grants access in case the client connected to the gRPC server
 // using a TLS client certificate that can be validated against the
 // chain of CAs used by the server.
-func NewTLSClientCertificateAuthenticator(clientCAs *x509.CertPool, clock clock.Clock) Authenticator {
-	return &tlsClientCertificateAuthenticator{
-		clientCAs: clientCAs,
-		clock:     clock,
+//
+// If a non-empty list of allowed subjects or a pattern is provided,
+// access is only granted in case the common name or one of the DNS
+// Subject Alternative Names of the client certificate is part of this
+// list, or is matched by the pattern.
+func NewTLSClientCertificateAuthenticator(clientCAs *x509.CertPool, clock clock.Clock, allowedSubjects []string, allowedSubjectPattern *regexp.Regexp) Authenticator {
+	a := &tlsClientCertificateAuthenticator{
+		clientCAs:             clientCAs,
+		clock:                 clock,
+		restrictSubjects:      len(allowedSubjects) > 0 || allowedSubjectPattern != nil,
+		allowedSubjects:       make(map[string]struct{}, len(allowedSubjects)),
+		allowedSubjectPattern: allowedSubjectPattern,
 	}
+	for _, subject := range allowedSubjects {
+		a.allowedSubjects[subject] = struct{}{}
+	}
+	return a
+}
+
+func (a *tlsClientCertificateAuthenticator) isAllowedName(name string) bool {
+	if _, ok := a.allowedSubjects[name]; ok {
+		return true
+	}
+	return a.allowedSubjectPattern != nil && a.allowedSubjectPattern.MatchString(name)
+}
+
+func (a *tlsClientCertificateAuthenticator) isAllowedSubject(cert *x509.Certificate) bool {
+	if !a.restrictSubjects {
+		return true
+	}
+	if a.isAllowedName(cert.Subject.CommonName) {
+		return true
+	}
+	for _, dnsName := range cert.DNSNames {
+		if a.isAllowedName(dnsName) {
+			return true
+		}
+	}
+	return false
 }
 
 func (a *tlsClientCertificateAuthenticator) Authenticate(ctx context.Context) error {
@@ -58,5 +96,8 @@ func (a *tlsClientCertificateAuthenticator) Authenticate(ctx context.Context) er
 	if _, err := certs[0].Verify(opts); err != nil {
 		return util.StatusWrapWithCode(err, codes.Unauthenticated, "Cannot validate TLS client certificate")
 	}
+	if !a.isAllowedSubject(certs[0]) {
+		return status.Errorf(codes.Unauthenticated, "TLS client certificate with common name %#v and DNS Subject Alternative Names %q is not permitted", certs[0].Subject.CommonName, certs[0].DNSNames)
+	}
 	return nil
 }
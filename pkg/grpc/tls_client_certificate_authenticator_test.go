@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"regexp"
 	"testing"
 	"time"
 
@@ -92,7 +93,7 @@ func TestTLSClientCertificateAuthenticator(t *testing.T) {
 	clientCAs := x509.NewCertPool()
 	clientCAs.AddCert(certificateValid)
 	clock := mock.NewMockClock(ctrl)
-	authenticator := bb_grpc.NewTLSClientCertificateAuthenticator(clientCAs, clock)
+	authenticator := bb_grpc.NewTLSClientCertificateAuthenticator(clientCAs, clock, nil, nil)
 
 	t.Run("NoGRPC", func(t *testing.T) {
 		// Authenticator is used outside of gRPC, meaning it cannot
@@ -186,3 +187,105 @@ func TestTLSClientCertificateAuthenticator(t *testing.T) {
 					})))
 	})
 }
+
+func TestTLSClientCertificateAuthenticatorAllowedSubjects(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(certificateValid)
+	clientCAs.AddCert(certificateUnrelated)
+	clock := mock.NewMockClock(ctrl)
+	authenticator := bb_grpc.NewTLSClientCertificateAuthenticator(clientCAs, clock, []string{"a.example.com"}, nil)
+
+	t.Run("Allowed", func(t *testing.T) {
+		// The common name of the certificate is part of the
+		// list of allowed subjects.
+		clock.EXPECT().Now().Return(time.Unix(1600000000, 0))
+		require.NoError(
+			t,
+			authenticator.Authenticate(
+				peer.NewContext(
+					ctx,
+					&peer.Peer{
+						AuthInfo: credentials.TLSInfo{
+							State: tls.ConnectionState{
+								PeerCertificates: []*x509.Certificate{
+									certificateValid,
+								},
+							},
+						},
+					})))
+	})
+
+	t.Run("Disallowed", func(t *testing.T) {
+		// The certificate can be validated, but its common name
+		// is not part of the list of allowed subjects.
+		clock.EXPECT().Now().Return(time.Unix(1600000000, 0))
+		require.Equal(
+			t,
+			status.Error(codes.Unauthenticated, "TLS client certificate with common name \"b.example.com\" and DNS Subject Alternative Names [] is not permitted"),
+			authenticator.Authenticate(
+				peer.NewContext(
+					ctx,
+					&peer.Peer{
+						AuthInfo: credentials.TLSInfo{
+							State: tls.ConnectionState{
+								PeerCertificates: []*x509.Certificate{
+									certificateUnrelated,
+								},
+							},
+						},
+					})))
+	})
+}
+
+func TestTLSClientCertificateAuthenticatorAllowedSubjectPattern(t *testing.T) {
+	ctrl, ctx := gomock.WithContext(context.Background(), t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(certificateValid)
+	clientCAs.AddCert(certificateUnrelated)
+	clock := mock.NewMockClock(ctrl)
+	authenticator := bb_grpc.NewTLSClientCertificateAuthenticator(clientCAs, clock, nil, regexp.MustCompile("^a\\.example\\.[a-z]+$"))
+
+	t.Run("Allowed", func(t *testing.T) {
+		// The common name of the certificate is matched by the
+		// pattern.
+		clock.EXPECT().Now().Return(time.Unix(1600000000, 0))
+		require.NoError(
+			t,
+			authenticator.Authenticate(
+				peer.NewContext(
+					ctx,
+					&peer.Peer{
+						AuthInfo: credentials.TLSInfo{
+							State: tls.ConnectionState{
+								PeerCertificates: []*x509.Certificate{
+									certificateValid,
+								},
+							},
+						},
+					})))
+	})
+
+	t.Run("Disallowed", func(t *testing.T) {
+		// The common name of the certificate is not matched by
+		// the pattern.
+		clock.EXPECT().Now().Return(time.Unix(1600000000, 0))
+		require.Equal(
+			t,
+			status.Error(codes.Unauthenticated, "TLS client certificate with common name \"b.example.com\" and DNS Subject Alternative Names [] is not permitted"),
+			authenticator.Authenticate(
+				peer.NewContext(
+					ctx,
+					&peer.Peer{
+						AuthInfo: credentials.TLSInfo{
+							State: tls.ConnectionState{
+								PeerCertificates: []*x509.Certificate{
+									certificateUnrelated,
+								},
+							},
+						},
+					})))
+	})
+}